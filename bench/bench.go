@@ -0,0 +1,261 @@
+// Package bench holds what every benchmark does around its workload:
+// connecting to both backends, putting the tables into their seed state and,
+// once the workload ran, saving the run and exiting with its status.
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"benchmarkDB/fixture"
+	"benchmarkDB/pool"
+	"benchmarkDB/report"
+	"benchmarkDB/results"
+	"benchmarkDB/runctx"
+	"benchmarkDB/server"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Addresses of the benchmarked servers
+const (
+	mongoURI = "mongodb://localhost:27017"
+	mysqlDSN = "MYSQL_USERNAME:SQL_PASS@tcp(localhost:3306)/MYSQL_DATABASE"
+)
+
+// MongoOptions are the options of a client of the benchmarked MongoDB server
+// with the given pools
+func MongoOptions(connections pool.Config) *options.ClientOptions {
+	return connections.Mongo(options.Client().ApplyURI(mongoURI))
+}
+
+// MySQLDSN is the data source name of the benchmarked MySQL server. params
+// are added to it, e.g. "tls=true", none when empty.
+func MySQLDSN(params string) string {
+	if params == "" {
+		return mysqlDSN
+	}
+	return mysqlDSN + "?" + params
+}
+
+// Mongo connects a client to the benchmarked MongoDB server with the given
+// pools
+func Mongo(ctx context.Context, connections pool.Config) (*mongo.Client, error) {
+	return ConnectMongo(ctx, MongoOptions(connections))
+}
+
+// MySQL opens a handle to the benchmarked MySQL server with the given pools
+func MySQL(ctx context.Context, connections pool.Config) (*sql.DB, error) {
+	return OpenMySQL(ctx, MySQLDSN(""), connections)
+}
+
+// ConnectMongo connects a MongoDB client and pings the server, which is when
+// the driver connects and authenticates
+func ConnectMongo(ctx context.Context, clientOptions *options.ClientOptions) (*mongo.Client, error) {
+	client, err := mongo.Connect(ctx, clientOptions)
+	pool.Track(client, clientOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the connection
+	err = runctx.Do(ctx, func(ctx context.Context) error { return client.Ping(ctx, nil) })
+	if err != nil {
+		DisconnectMongo(client)
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// DisconnectMongo disconnects a client of ConnectMongo and drops its pool
+// monitor
+func DisconnectMongo(client *mongo.Client) {
+	client.Disconnect(context.Background())
+	pool.Forget(client)
+}
+
+// OpenMySQL opens a MySQL handle with the given pools and pings the server,
+// which is when the driver connects and authenticates
+func OpenMySQL(ctx context.Context, dsn string, connections pool.Config) (*sql.DB, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+	connections.MySQL(db)
+
+	// Check the connection
+	err = runctx.Do(ctx, db.PingContext)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Bench is a benchmark run against the tables on both backends
+type Bench struct {
+	Mongo  *mongo.Client // nil when the benchmark connects on its own
+	MySQL  *sql.DB       // nil when the benchmark connects on its own
+	ctx    context.Context
+	stop   func()
+	tables []string
+	after  []func() error
+
+	// Clients the server metrics are polled over, when they are on
+	pollMongo *mongo.Client
+	pollMySQL *sql.DB
+}
+
+// pollers are the pools of the clients the server metrics are polled over,
+// one connection each
+var pollers = pool.Config{MySQLMaxOpenConns: 1, MySQLMaxIdleConns: 1, MongoMaxPoolSize: 1}
+
+// Begin starts a run that connects to the backends on its own. It is stopped
+// by the run deadline or SIGINT, keeping the results so far.
+func Begin() (context.Context, *Bench) {
+	ctx, stop := runctx.Start()
+	return ctx, &Bench{ctx: ctx, stop: stop}
+}
+
+// Start starts a run, connects to both backends with the default pools and
+// resets the tables to the seed data, which is not part of the results. The
+// program exits when any of it fails.
+func Start(tables []string) (context.Context, *Bench) {
+	ctx, b := Begin()
+	b.tables = tables
+
+	var err error
+	b.Mongo, err = Mongo(ctx, pool.Default)
+	if err != nil {
+		Fail("Error initializing MongoDB client:", err)
+	}
+	b.MySQL, err = MySQL(ctx, pool.Default)
+	if err != nil {
+		Fail("Error initializing MySQL client:", err)
+	}
+
+	// Poll the server metrics over clients of their own
+	if server.Metrics {
+		b.pollMongo, err = Mongo(ctx, pollers)
+		if err != nil {
+			Fail("Error initializing the MongoDB metrics client:", err)
+		}
+		b.pollMySQL, err = MySQL(ctx, pollers)
+		if err != nil {
+			Fail("Error initializing the MySQL metrics client:", err)
+		}
+		server.PollOver(b.pollMongo, b.pollMySQL)
+	}
+
+	// Start every run from the seed data, the reset is not part of the results
+	if len(tables) > 0 {
+		if err := fixture.Setup(ctx, b.Mongo, b.MySQL, tables); err != nil {
+			Fail("Error resetting tables:", err)
+		}
+	}
+	return ctx, b
+}
+
+// Servers describes the servers of both clients
+func (b *Bench) Servers() []results.Server {
+	return server.DescribeAll(b.ctx, b.Mongo, b.MySQL)
+}
+
+// After adds a step run once the results are saved and before the tables are
+// restored, e.g. dropping tables of the benchmark's own. Its error, which
+// says what failed, fails the run.
+func (b *Bench) After(step func() error) {
+	b.after = append(b.after, step)
+}
+
+// Finish ends the run: it records why the run stopped early, if it did,
+// prints the summary, saves the results, runs the After steps, restores the
+// tables and exits with status 1 when any operation or any of these failed.
+func (b *Bench) Finish(run *results.Run) {
+	if run.Finished.IsZero() {
+		run.Finished = time.Now()
+	}
+	run.Stopped = runctx.Reason(b.ctx)
+	if run.Stopped != "" {
+		fmt.Println("Run stopped early:", run.Stopped)
+	}
+	report.WriteSummary(os.Stdout, run)
+	failed := run.Failed()
+	if path, err := run.Save(); err != nil {
+		fmt.Println("Error saving results:", err)
+		failed = true
+	} else {
+		fmt.Println("Results saved to", path)
+	}
+	for _, step := range b.after {
+		if err := step(); err != nil {
+			fmt.Println("Error finishing the run:", err)
+			failed = true
+		}
+	}
+	if b.Mongo != nil && b.MySQL != nil && len(b.tables) > 0 {
+		if err := fixture.Teardown(b.Mongo, b.MySQL, b.tables); err != nil {
+			fmt.Println("Error restoring tables:", err)
+			failed = true
+		}
+	}
+
+	b.close()
+	Exit(failed)
+}
+
+func (b *Bench) close() {
+	if b.Mongo != nil {
+		DisconnectMongo(b.Mongo)
+	}
+	if b.MySQL != nil {
+		b.MySQL.Close()
+	}
+	if b.pollMongo != nil {
+		DisconnectMongo(b.pollMongo)
+	}
+	if b.pollMySQL != nil {
+		b.pollMySQL.Close()
+	}
+	b.stop()
+}
+
+// Fail prints why the program cannot go on and exits with status 1
+func Fail(a ...interface{}) {
+	fmt.Println(a...)
+	Exit(true)
+}
+
+// Exit ends the program with status 1 when failed, 0 otherwise
+func Exit(failed bool) {
+	if failed {
+		fmt.Println("Program completed with errors")
+		os.Exit(1) // Exit with non-zero exit code to indicate failure
+	}
+
+	fmt.Println("Program completed successfully")
+	os.Exit(0)
+}
+
+// PrintErrors prints each distinct error of a set of operations once, with
+// how many of the operations failed with it. doing describes them, e.g.
+// "reading table1 in MongoDB".
+func PrintErrors(doing string, errs []error) {
+	counts := make(map[string]int)
+	var messages []string
+	for _, err := range errs {
+		if counts[err.Error()] == 0 {
+			messages = append(messages, err.Error())
+		}
+		counts[err.Error()]++
+	}
+	for _, m := range messages {
+		fmt.Printf("Error %s (%d times): %s\n", doing, counts[m], m)
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"benchmarkDB/connect"
+	"benchmarkDB/create"
+	"benchmarkDB/delete"
+	"benchmarkDB/read"
+	"benchmarkDB/report"
+	"benchmarkDB/results"
+	"benchmarkDB/update"
+	"benchmarkDB/upsert"
+	"benchmarkDB/ycsb"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const usage = `usage: benchmarkDB [global flags] [command] [flags]
+
+Without a command the interactive menu is shown.
+
+Global flags:
+  -plots-dir dir       directory charts are written to (default ./plots)
+  -plots-prefix name   prefix of chart file names (default plot_)
+  -plots-format fmt    chart file format: png, svg or pdf (default png)
+  -plots-width inches  chart width (default 10)
+  -plots-height inches chart height (default 6)
+  -plots-dpi n         resolution of png charts (default 96)
+  -deadline d          stop the whole run after d, e.g. 10m, keeping the
+                       partial results (default no limit)
+  -op-timeout d        time out a single database operation after d
+                       (default 30s, 0 for no limit)
+  -reset=false         keep the data in the tables instead of resetting them
+                       to dataset/*.csv before and after each benchmark
+  -server-metrics      poll MySQL SHOW GLOBAL STATUS and MongoDB serverStatus
+                       while each operation runs and record the changes
+  -server-metrics-interval d
+                       time between two polls of the servers (default 1s)
+  -explain             explain the query of each operation, EXPLAIN FORMAT=JSON
+                       on MySQL and executionStats on MongoDB, and record
+                       the index used and the rows examined
+  -mysql-max-open-conns n
+                       most MySQL connections open at once (default 0, no limit)
+  -mysql-max-idle-conns n
+                       most idle MySQL connections kept open (default 2)
+  -mysql-conn-max-lifetime d
+                       longest a MySQL connection is reused (default 0, no limit)
+  -mongo-max-pool-size n
+                       most MongoDB connections per server (default 100)
+  -mongo-min-pool-size n
+                       MongoDB connections kept open when idle (default 0)
+  -mongo-max-conn-idle-time d
+                       longest a MongoDB connection stays idle (default 0, no
+                       limit)
+
+Commands:
+  create    compare insert latencies
+  read      compare read latencies
+  update    compare updates by key and by filter, increments, upserts and
+            concurrent read-modify-writes, e.g. update -workers 4
+  delete    compare delete latencies by key, by filter and in bulk, e.g.
+            delete -bulk-rows 100 -workers 4
+  upsert    compare insert-or-update by Name and Year, e.g.
+            upsert -operations 1000 -hit-ratio 0.5
+  connect   time cold connect, authentication and ping per backend, e.g.
+            connect -attempts 20 -tls
+  ycsb      run a YCSB core workload (a-f)
+  sweep     run a YCSB core workload at increasing concurrency
+  pool-sweep
+            run a YCSB core workload at the same concurrency with
+            increasing pool sizes, e.g. pool-sweep -workers 32 -sizes 1,4,16,32
+  report    write an HTML or Markdown report of a results file
+  history   list the runs recorded in results/history.jsonl
+  compare   show the changes between two runs, e.g. compare <runA> <runB>
+  check     fail when a run regressed against a baseline, e.g.
+            check -baseline base.json -max-regression 10% [run]
+`
+
+func runCommand(args []string) error {
+	switch args[0] {
+	case "create":
+		create.Create()
+	case "read":
+		read.Read()
+	case "update":
+		fs := flag.NewFlagSet("update", flag.ExitOnError)
+		fs.IntVar(&update.Default.Workers, "workers", update.Default.Workers, "concurrent clients of the read-modify-writes")
+		fs.Parse(args[1:])
+		if err := update.Default.Validate(); err != nil {
+			return err
+		}
+		update.Update()
+	case "delete":
+		fs := flag.NewFlagSet("delete", flag.ExitOnError)
+		fs.IntVar(&delete.Default.BulkRows, "bulk-rows", delete.Default.BulkRows, "rows removed by one bulk delete")
+		fs.IntVar(&delete.Default.Workers, "workers", delete.Default.Workers, "concurrent clients of the multi-threaded delete")
+		fs.Parse(args[1:])
+		if err := delete.Default.Validate(); err != nil {
+			return err
+		}
+		delete.Delete()
+	case "upsert":
+		fs := flag.NewFlagSet("upsert", flag.ExitOnError)
+		fs.IntVar(&upsert.Default.Operations, "operations", upsert.Default.Operations, "upserts per backend, table and method")
+		fs.Float64Var(&upsert.Default.HitRatio, "hit-ratio", upsert.Default.HitRatio, "share of the upserts on keys that already exist, 0 to 1")
+		fs.Parse(args[1:])
+		if err := upsert.Default.Validate(); err != nil {
+			return err
+		}
+		upsert.Upsert()
+	case "connect":
+		fs := flag.NewFlagSet("connect", flag.ExitOnError)
+		fs.IntVar(&connect.Default.Attempts, "attempts", connect.Default.Attempts, "cold connections per backend")
+		fs.BoolVar(&connect.Default.TLS, "tls", connect.Default.TLS, "connect over TLS")
+		fs.BoolVar(&connect.Default.SkipVerify, "tls-skip-verify", connect.Default.SkipVerify, "accept any server certificate, e.g. a self-signed one")
+		fs.Parse(args[1:])
+		if err := connect.Default.Validate(); err != nil {
+			return err
+		}
+		connect.Connect()
+	case "ycsb":
+		fs := flag.NewFlagSet("ycsb", flag.ExitOnError)
+		workload, opts := ycsbFlags(fs)
+		fs.Parse(args[1:])
+
+		if err := checkYCSBFlags(fs, *workload, opts); err != nil {
+			return err
+		}
+		ycsb.RunWorkload(*workload, *opts)
+	case "sweep":
+		fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+		workload, opts := ycsbFlags(fs)
+		workers := fs.String("workers", "1,2,4,8,16,32", "comma separated worker counts to run at")
+		table := fs.String("table", "table1", "table to run against, or \"all\"")
+		fs.Parse(args[1:])
+
+		levels, err := ycsb.ParseSweep(*workers)
+		if err != nil {
+			return fmt.Errorf("worker counts: %w", err)
+		}
+		opts.Workers = levels[0]
+		if err := checkYCSBFlags(fs, *workload, opts); err != nil {
+			return err
+		}
+		tables := []string{*table}
+		if *table == "all" {
+			tables = []string{"table1", "table2", "table3", "table4"}
+		}
+		ycsb.RunSweep(*workload, *opts, tables, levels)
+	case "pool-sweep":
+		fs := flag.NewFlagSet("pool-sweep", flag.ExitOnError)
+		workload, opts := ycsbFlags(fs)
+		fs.IntVar(&opts.Workers, "workers", 32, "number of concurrent workers")
+		sizes := fs.String("sizes", "1,2,4,8,16,32", "comma separated pool sizes to run with")
+		table := fs.String("table", "table1", "table to run against, or \"all\"")
+		fs.Parse(args[1:])
+
+		levels, err := ycsb.ParseSweep(*sizes)
+		if err != nil {
+			return fmt.Errorf("pool sizes: %w", err)
+		}
+		if err := checkYCSBFlags(fs, *workload, opts); err != nil {
+			return err
+		}
+		tables := []string{*table}
+		if *table == "all" {
+			tables = []string{"table1", "table2", "table3", "table4"}
+		}
+		ycsb.RunPoolSweep(*workload, *opts, tables, levels)
+	case "report":
+		fs := flag.NewFlagSet("report", flag.ExitOnError)
+		format := fs.String("format", "html", "report format: html or markdown")
+		out := fs.String("o", "", "file to write, default the results file with an .html or .md extension")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return errors.New("usage: report [-format html|markdown] [-o file] results/<run>.json")
+		}
+		return writeReport(fs.Arg(0), *format, *out)
+	case "history":
+		runs, err := results.History()
+		if err != nil {
+			return err
+		}
+		for _, run := range runs {
+			fmt.Printf("%-40s %s  %d results\n", run.ID, run.Started.Format("2006-01-02 15:04:05"), len(run.Results))
+		}
+	case "compare":
+		if len(args) != 3 {
+			return errors.New("usage: compare <runA> <runB> (run IDs, ID prefixes or result files)")
+		}
+		before, err := results.Find(args[1])
+		if err != nil {
+			return err
+		}
+		after, err := results.Find(args[2])
+		if err != nil {
+			return err
+		}
+		return report.WriteDiff(os.Stdout, before, after)
+	case "check":
+		fs := flag.NewFlagSet("check", flag.ExitOnError)
+		baseline := fs.String("baseline", "", "baseline results file or run ID (required)")
+		maxRegression := fs.String("max-regression", "10%", "largest accepted regression of p95 latency and throughput")
+		thresholds := thresholdFlag{}
+		fs.Var(thresholds, "threshold", "limit of one operation, e.g. -threshold \"single-threaded read=20%\" (repeatable)")
+		fs.Parse(args[1:])
+		if *baseline == "" || fs.NArg() > 1 {
+			return errors.New("usage: check -baseline base.json [-max-regression 10%] [-threshold op=N%]... [run]")
+		}
+		limit, err := parsePercent(*maxRegression)
+		if err != nil {
+			return err
+		}
+		return checkRun(*baseline, fs.Arg(0), report.Limits{Default: limit, PerOperation: thresholds})
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Print(usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return nil
+}
+
+// writeReport renders the results file at path as HTML or Markdown
+func writeReport(path, format, out string) error {
+	ext := map[string]string{"html": ".html", "markdown": ".md", "md": ".md"}[format]
+	if ext == "" {
+		return fmt.Errorf("unknown report format %q (expected html or markdown)", format)
+	}
+	run, err := results.Load(path)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		out = strings.TrimSuffix(path, filepath.Ext(path)) + ext
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if ext == ".md" {
+		err = report.Markdown(f, run, filepath.Dir(out))
+	} else {
+		err = report.HTML(f, run)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Println("Report written to", out)
+	return nil
+}
+
+// checkRun compares a run with the baseline. Without a run the latest run of
+// the baseline's benchmark in the history is checked.
+func checkRun(baselineRef, currentRef string, limits report.Limits) error {
+	baseline, err := results.Find(baselineRef)
+	if err != nil {
+		return err
+	}
+	var current *results.Run
+	if currentRef == "" {
+		current, err = results.Latest(baseline.Benchmark, baseline.ID)
+	} else {
+		current, err = results.Find(currentRef)
+	}
+	if err != nil {
+		return err
+	}
+
+	passed, err := report.WriteChecks(os.Stdout, baseline, current, report.CheckRegressions(baseline, current, limits))
+	if err != nil {
+		return err
+	}
+	if !passed {
+		return errors.New("regression check failed")
+	}
+	return nil
+}
+
+// thresholdFlag collects per-operation limits given as operation=percent
+type thresholdFlag map[string]float64
+
+func (t thresholdFlag) String() string {
+	return fmt.Sprint(map[string]float64(t))
+}
+
+func (t thresholdFlag) Set(value string) error {
+	i := strings.LastIndex(value, "=")
+	if i <= 0 {
+		return fmt.Errorf("threshold %q is not operation=percent", value)
+	}
+	limit, err := parsePercent(value[i+1:])
+	if err != nil {
+		return err
+	}
+	t[strings.TrimSpace(value[:i])] = limit
+	return nil
+}
+
+// parsePercent reads "10%" or "10" as 10
+func parsePercent(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	return v, nil
+}
+
+// ycsbFlags registers the flags shared by the YCSB commands
+func ycsbFlags(fs *flag.FlagSet) (*string, *ycsb.Options) {
+	opts := ycsb.DefaultOptions()
+	workload := fs.String("workload", "a", "YCSB core workload to run ("+strings.Join(ycsb.Names(), ", ")+")")
+	fs.IntVar(&opts.OperationCount, "operations", ycsb.DefaultOperationCount, "number of operations per table and backend, 0 for no limit")
+	fs.DurationVar(&opts.Duration, "duration", 0, "run each table for this long, e.g. 30s (implies -operations 0 unless set)")
+	fs.Float64Var(&opts.TargetRate, "target", 0, "target throughput in ops/sec with open-loop scheduling, 0 to run as fast as possible")
+	if fs.Name() == "ycsb" {
+		fs.IntVar(&opts.Workers, "workers", 1, "number of concurrent workers")
+	}
+	fs.DurationVar(&opts.SampleInterval, "sample-interval", ycsb.DefaultSampleInterval, "how often to sample throughput and latency, 0 to disable")
+	return workload, &opts
+}
+
+// checkYCSBFlags validates the parsed YCSB flags
+func checkYCSBFlags(fs *flag.FlagSet, workload string, opts *ycsb.Options) error {
+	if opts.Duration > 0 && !flagSet(fs, "operations") {
+		opts.OperationCount = 0
+	}
+	if _, err := ycsb.Lookup(workload); err != nil {
+		return err
+	}
+	return opts.Validate()
+}
+
+// flagSet reports whether a flag was passed explicitly
+func flagSet(fs *flag.FlagSet, name string) bool {
+	found := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
+}
@@ -0,0 +1,198 @@
+// Package connect measures what the other benchmarks pay before their first
+// operation: opening a client, connecting, authenticating and pinging the
+// server, cold, on each backend.
+package connect
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"benchmarkDB/bench"
+	"benchmarkDB/pool"
+	"benchmarkDB/report/plot"
+	"benchmarkDB/results"
+	"benchmarkDB/runctx"
+	"benchmarkDB/server"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Options of the connection benchmark
+type Options struct {
+	Attempts   int  // cold connections per backend
+	TLS        bool // connect over TLS
+	SkipVerify bool // accept any server certificate, e.g. a self-signed one
+}
+
+// Default options, set from the command line
+var Default = Options{Attempts: 20}
+
+// Validate checks that there is something to run
+func (o Options) Validate() error {
+	if o.Attempts < 1 {
+		return errors.New("attempts must be at least 1")
+	}
+	if o.SkipVerify && !o.TLS {
+		return errors.New("skip verify requires tls")
+	}
+	return nil
+}
+
+// Operations recorded in the results
+const (
+	coldConnect = "cold connect" // new client, connect, authenticate and ping
+	warmPing    = "ping"         // a second ping on the connection just opened
+)
+
+// Table the results are recorded against, connecting reads no table
+const table = "server"
+
+func Connect() {
+	opts := Default
+
+	// Connecting is what is measured, so the run has no clients of its own
+	ctx, b := bench.Begin()
+
+	run := results.NewRun("connect", results.Config{OperationCount: opts.Attempts, TLS: opts.TLS})
+
+	// Describing the servers also connects once to each, so the attempts
+	// below do not pay for the server's first connection
+	servers := make([]results.Server, 0, 2)
+	for _, backend := range []string{"MongoDB", "MySQL"} {
+		client, disconnect, err := dial(ctx, backend, opts)
+		if err != nil {
+			fmt.Printf("Error connecting to %s: %v\n", backend, err)
+			servers = append(servers, results.Server{Backend: backend, Error: err.Error()})
+			continue
+		}
+		servers = append(servers, server.Describe(ctx, backend, client))
+		disconnect()
+	}
+	run.Environment.Servers = servers
+
+	fmt.Printf("************Connecting %d times to each backend (TLS %v)***************\n", opts.Attempts, opts.TLS)
+	for _, backend := range []string{"MongoDB", "MySQL"} {
+		if ctx.Err() != nil {
+			break
+		}
+		connects, pings, connectErrs, pingErrs := timedConnects(ctx, backend, opts)
+		for _, err := range connectErrs {
+			fmt.Printf("Error connecting to %s: %v\n", backend, err)
+		}
+		for _, err := range pingErrs {
+			fmt.Printf("Error pinging %s on an open connection: %v\n", backend, err)
+		}
+		cold := results.Iterations(backend, table, coldConnect, connects)
+		run.Add(cold, connectErrs...)
+		warm := results.Iterations(backend, table, warmPing, pings)
+		run.Add(warm, pingErrs...)
+		if len(connects) > 0 {
+			fmt.Printf("    Mean time of %s cold connect: %v, ping on the open connection: %v\n", backend, results.Micros(cold.Latency.Mean), results.Micros(warm.Latency.Mean))
+		}
+	}
+	fmt.Println("*************************************************************")
+
+	// Plotting
+	run.Finished = time.Now()
+	operation := func(r results.Result) string { return r.Operation }
+	title := "Time to connect, authenticate and ping"
+	if opts.TLS {
+		title += " over TLS"
+	}
+	if err := plot.Bars("connect", title, "Operation", run.Results, operation, plot.Mean); err != nil {
+		fmt.Println("Error saving plot:", err)
+	}
+	var cold []results.Result
+	for _, r := range run.Results {
+		if r.Operation == coldConnect {
+			cold = append(cold, r)
+		}
+	}
+	if err := plot.Distributions("connect", "Cold connect", cold, operation); err != nil {
+		fmt.Println("Error saving plot:", err)
+	}
+	b.Finish(run)
+}
+
+// timedConnects opens opts.Attempts clients of a backend one after the other
+// and returns the time each took to connect and answer its first ping, the
+// time of a second ping on the same connection, both in seconds, and the
+// errors of the connects and of the second pings that failed. Closing a
+// client is not timed. It returns early when the run is stopped.
+func timedConnects(ctx context.Context, backend string, opts Options) (connects, pings []float64, connectErrs, pingErrs []error) {
+	for i := 0; i < opts.Attempts; i++ {
+		start := time.Now()
+		client, disconnect, err := dial(ctx, backend, opts)
+		elapsed := time.Since(start)
+		if runctx.Stopped(err) {
+			break
+		}
+		if err != nil {
+			connectErrs = append(connectErrs, err)
+			continue
+		}
+		connects = append(connects, elapsed.Seconds())
+
+		start = time.Now()
+		err = runctx.Do(ctx, func(ctx context.Context) error { return ping(ctx, client) })
+		elapsed = time.Since(start)
+		disconnect()
+		if runctx.Stopped(err) {
+			break
+		}
+		if err != nil {
+			pingErrs = append(pingErrs, err)
+			continue
+		}
+		pings = append(pings, elapsed.Seconds())
+	}
+	return connects, pings, connectErrs, pingErrs
+}
+
+// dial opens a new client of a backend and pings the server, which is when
+// both drivers connect and authenticate, under the operation timeout.
+// disconnect closes the client.
+func dial(ctx context.Context, backend string, opts Options) (client interface{}, disconnect func(), err error) {
+	switch backend {
+	case "MongoDB":
+		clientOptions := bench.MongoOptions(pool.Default)
+		if opts.TLS {
+			clientOptions.SetTLSConfig(&tls.Config{InsecureSkipVerify: opts.SkipVerify})
+		}
+		mongoClient, err := bench.ConnectMongo(ctx, clientOptions)
+		if err != nil {
+			return nil, nil, err
+		}
+		return mongoClient, func() { bench.DisconnectMongo(mongoClient) }, nil
+	case "MySQL":
+		params := ""
+		if opts.SkipVerify {
+			params = "tls=skip-verify"
+		} else if opts.TLS {
+			params = "tls=true"
+		}
+		db, err := bench.OpenMySQL(ctx, bench.MySQLDSN(params), pool.Default)
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, func() { db.Close() }, nil
+	default:
+		return nil, nil, errors.New("unsupported backend")
+	}
+}
+
+// ping asks the server of an open client to answer
+func ping(ctx context.Context, client interface{}) error {
+	switch c := client.(type) {
+	case *mongo.Client:
+		return c.Ping(ctx, nil)
+	case *sql.DB:
+		return c.PingContext(ctx)
+	default:
+		return errors.New("unsupported client type")
+	}
+}
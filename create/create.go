@@ -5,24 +5,21 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"os"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"gonum.org/v1/plot"
-	"gonum.org/v1/plot/plotter"
-	"gonum.org/v1/plot/vg"
+
+	"benchmarkDB/bench"
+	"benchmarkDB/dataset"
+	"benchmarkDB/report/plot"
+	"benchmarkDB/results"
+	"benchmarkDB/runctx"
+	"benchmarkDB/server"
 )
 
-type Record struct {
-	Name       string
-	School     string
-	Job        string
-	Department string
-	Earnings   float64
-	Year       int
-}
+// Record is one row of the benchmarked tables
+type Record = dataset.Record
 
 func Create() {
 	// Dummy data to be inserted
@@ -46,200 +43,210 @@ func Create() {
 
 	tables := []string{"table1", "table2", "table3", "table4"} // Representing MongoDB collections or MySQL tables
 
-	var mongoClient *mongo.Client
-	var mysqlDB *sql.DB
-	var err error
-
-	// Initialize MongoDB client
-	mongoClient, err = initMongoClient()
-	if err != nil {
-		fmt.Println("Error initializing MongoDB client:", err)
-		return
-	}
-	defer mongoClient.Disconnect(context.Background())
-
-	// Initialize MySQL client
-	mysqlDB, err = initMySQLClient()
-	if err != nil {
-		fmt.Println("Error initializing MySQL client:", err)
-		return
-	}
-	defer mysqlDB.Close()
+	// Connected to both backends, starting from the seed data
+	ctx, b := bench.Start(tables)
+	mongoClient, mysqlDB := b.Mongo, b.MySQL
 
 	// Collect time taken for inserts
-	var singleThreadedMongoDBTime, singleThreadedMySQLTime, multiThreadedMongoDBTime, multiThreadedMySQLTime time.Duration
+	run := results.NewRun("create", results.Config{})
+	run.Environment.Servers = b.Servers()
 
-	// Single Threaded
+	// Single Threaded, timing each insert for the latency distribution. The
+	// total is only recorded when every insert succeeded, the failures are in
+	// the results of each table.
 	fmt.Println("************Performing single-threaded inserts***************")
-	start := time.Now()
-	err = SingleThreadedInsert(mongoClient, tables, data1)
-	if err != nil {
-		fmt.Println("Error inserting data into MongoDB:", err)
-	} else {
-		singleThreadedMongoDBTime = time.Since(start)
-		fmt.Println("Time taken for single-threaded MongoDB insert:", singleThreadedMongoDBTime)
-	}
-
-	start = time.Now()
-	err = SingleThreadedInsert(mysqlDB, tables, data1)
-	if err != nil {
-		fmt.Println("Error inserting data into MySQL:", err)
-	} else {
-		singleThreadedMySQLTime = time.Since(start)
-		fmt.Println("Time taken for single-threaded MySQL insert:", singleThreadedMySQLTime)
+	singleThreaded := func(backend string, client interface{}) {
+		start := time.Now()
+		if !timedInserts(ctx, run, backend, client, tables, data1) {
+			return
+		}
+		elapsed := time.Since(start)
+		fmt.Println("Time taken for single-threaded", backend, "insert:", elapsed)
+		run.Add(results.Timing(backend, "all", "Single-threaded", elapsed, len(tables)*len(data1)))
 	}
+	singleThreaded("MongoDB", mongoClient)
+	singleThreaded("MySQL", mysqlDB)
 	fmt.Println("*************************************************************")
 
-	// Multi Threaded
+	// Multi Threaded, timed until all the inserts of a backend finished
 	fmt.Println("************Performing multi-threaded inserts***************")
-	start = time.Now()
-	err = MultiThreadedInsert(mongoClient, tables, data2)
-	if err != nil {
-		fmt.Println("Error inserting data into multi-threaded MongoDB:", err)
-	} else {
-		multiThreadedMongoDBTime = time.Since(start)
-		fmt.Println("Time taken for multi-threaded MongoDB insert:", multiThreadedMongoDBTime)
-	}
+	multiThreaded := func(backend string, client interface{}) {
+		var (
+			mu      sync.Mutex
+			errs    []error
+			pending sync.WaitGroup
+		)
+		failed := func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		}
+		watch := server.Watch(ctx, client, server.Report(run, backend, "all"))
+		start := time.Now()
+		if err := MultiThreadedInsert(ctx, client, tables, data2, &pending, failed); err != nil {
+			failed(err)
+		}
+		pending.Wait()
+		elapsed := time.Since(start)
+		for _, err := range errs {
+			fmt.Printf("Error inserting data into multi-threaded %s: %v\n", backend, err)
+		}
+		fmt.Println("Time taken for multi-threaded", backend, "insert:", elapsed)
 
-	start = time.Now()
-	err = MultiThreadedInsert(mysqlDB, tables, data2)
-	if err != nil {
-		fmt.Println("Error inserting data into multi-threaded MySQL:", err)
-	} else {
-		multiThreadedMySQLTime = time.Since(start)
-		fmt.Println("Time taken for multi-threaded MySQL insert:", multiThreadedMySQLTime)
+		r := results.Timing(backend, "all", "Multi-threaded", elapsed, len(tables)*len(data2)-len(errs))
+		r.Server = watch.Stop()
+		run.Add(r, errs...)
 	}
+	multiThreaded("MongoDB", mongoClient)
+	multiThreaded("MySQL", mysqlDB)
 	fmt.Println("*************************************************************")
 
 	// Plotting the graph
-	plotGraph(singleThreadedMongoDBTime, singleThreadedMySQLTime, multiThreadedMongoDBTime, multiThreadedMySQLTime)
-
-	if err != nil {
-		fmt.Println("Program completed with errors")
-		os.Exit(1)
-	}
-
-	fmt.Println("Program completed successfully")
-	os.Exit(0)
-}
-
-func initMongoClient() (*mongo.Client, error) {
-	clientOptions := options.Client().ApplyURI("mongodb://localhost:27017")
-	client, err := mongo.Connect(context.Background(), clientOptions)
-	if err != nil {
-		return nil, err
-	}
-
-	err = client.Ping(context.Background(), nil)
-	if err != nil {
-		return nil, err
-	}
-
-	return client, nil
+	run.Finished = time.Now()
+	plotGraph(run)
+	b.Finish(run)
 }
 
-func initMySQLClient() (*sql.DB, error) {
-	db, err := sql.Open("mysql", ":SQL_PASStcp(localhost:3306)/MYSQL_DATABASE")
-	if err != nil {
-		return nil, err
+// SingleThreadedInsert inserts the records into every table one at a time,
+// returning the errors of all failed inserts joined
+func SingleThreadedInsert(ctx context.Context, client interface{}, tables []string, data []Record) error {
+	var errs []error
+	for _, table := range tables {
+		_, failed := TimedInsert(ctx, client, table, data)
+		errs = append(errs, failed...)
 	}
-
-	err = db.Ping()
-	if err != nil {
-		return nil, err
-	}
-
-	return db, nil
+	return errors.Join(errs...)
 }
 
-func SingleThreadedInsert(client interface{}, tables []string, data []Record) error {
+// TimedInsert inserts the records one at a time and returns the latency of
+// each successful insert in seconds and the errors of the failed ones. It
+// returns early when the run is stopped.
+func TimedInsert(ctx context.Context, client interface{}, table string, data []Record) ([]float64, []error) {
+	latencies := make([]float64, 0, len(data))
+	var errs []error
 	switch c := client.(type) {
 	case *mongo.Client:
 		mongoClient := c
-		for _, table := range tables {
-			collection := mongoClient.Database("MONGODB_DATABASE").Collection(table)
-			for _, record := range data {
-				_, err := collection.InsertOne(context.Background(), record)
-				if err != nil {
-					return err
-				}
+		collection := mongoClient.Database("MONGODB_DATABASE").Collection(table)
+		for _, record := range data {
+			start := time.Now()
+			err := runctx.Do(ctx, func(ctx context.Context) error {
+				_, err := collection.InsertOne(ctx, record)
+				return err
+			})
+			if runctx.Stopped(err) {
+				break
+			}
+			if err != nil {
+				errs = append(errs, err)
+				continue
 			}
+			latencies = append(latencies, time.Since(start).Seconds())
 		}
-		return nil
+		return latencies, errs
 	case *sql.DB:
 		mysqlDB := c
-		for _, table := range tables {
-			query := "INSERT INTO " + table + " (Name, School, Job, Department, Earnings, Year) VALUES (?, ?, ?, ?, ?, ?)"
-			stmt, err := mysqlDB.Prepare(query)
-			if err != nil {
+		query := "INSERT INTO " + table + " (Name, School, Job, Department, Earnings, Year) VALUES (?, ?, ?, ?, ?, ?)"
+		var stmt *sql.Stmt
+		err := runctx.Do(ctx, func(ctx context.Context) error {
+			var err error
+			stmt, err = mysqlDB.PrepareContext(ctx, query)
+			return err
+		})
+		if runctx.Stopped(err) {
+			return latencies, nil
+		}
+		if err != nil {
+			return latencies, failAll(err, len(data))
+		}
+		defer stmt.Close()
+		for _, record := range data {
+			start := time.Now()
+			err := runctx.Do(ctx, func(ctx context.Context) error {
+				_, err := stmt.ExecContext(ctx, record.Name, record.School, record.Job, record.Department, record.Earnings, record.Year)
 				return err
+			})
+			if runctx.Stopped(err) {
+				break
 			}
-			defer stmt.Close()
-			for _, record := range data {
-				_, err := stmt.Exec(record.Name, record.School, record.Job, record.Department, record.Earnings, record.Year)
-				if err != nil {
-					return err
-				}
+			if err != nil {
+				errs = append(errs, err)
+				continue
 			}
+			latencies = append(latencies, time.Since(start).Seconds())
 		}
-		return nil
+		return latencies, errs
 	default:
-		return errors.New("unsupported client type")
+		return latencies, failAll(errors.New("unsupported client type"), len(data))
 	}
 }
 
-func MultiThreadedInsert(client interface{}, tables []string, data []Record) error {
-	switch c := client.(type) {
-	case *mongo.Client:
-		mongoClient := c
-		go func() {
-			err := SingleThreadedInsert(mongoClient, tables, data)
-			if err != nil {
-				fmt.Println("Error inserting data into MongoDB:", err)
-			}
-		}()
-		return nil
-	case *sql.DB:
-		mysqlDB := c
-		go func() {
-			err := SingleThreadedInsert(mysqlDB, tables, data)
-			if err != nil {
-				fmt.Println("Error inserting data into MySQL:", err)
-			}
-		}()
+// failAll is the error of each of n operations that could not be attempted
+func failAll(err error, n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
+
+// MultiThreadedInsert starts the inserts in the background, one goroutine per
+// table. pending is done once they finished and failed is called with each of
+// their errors, possibly concurrently.
+func MultiThreadedInsert(ctx context.Context, client interface{}, tables []string, data []Record, pending *sync.WaitGroup, failed func(error)) error {
+	switch client.(type) {
+	case *mongo.Client, *sql.DB:
+		for _, table := range tables {
+			pending.Add(1)
+			go func(table string) {
+				defer pending.Done()
+				_, errs := TimedInsert(ctx, client, table, data)
+				for _, err := range errs {
+					failed(err)
+				}
+			}(table)
+		}
 		return nil
 	default:
 		return errors.New("unsupported client type")
 	}
 }
 
-func plotGraph(singleThreadedMongoDBTime, singleThreadedMySQLTime, multiThreadedMongoDBTime, multiThreadedMySQLTime time.Duration) {
-	p := plot.New()
-
-	// Create data points for the plot
-	data := plotter.Values{singleThreadedMongoDBTime.Seconds(), singleThreadedMySQLTime.Seconds(), multiThreadedMongoDBTime.Seconds(), multiThreadedMySQLTime.Seconds()}
-	labels := []string{"Single-Threaded MongoDB", "Single-Threaded MySQL", "Multi-Threaded MongoDB", "Multi-Threaded MySQL"}
-
-	// Create a horizontal bar chart
-	bars, err := plotter.NewBarChart(data, vg.Points(50))
-	if err != nil {
-		fmt.Println("Error creating bar chart:", err)
-		return
+// timedInserts inserts the records into every table and keeps the latency of
+// each insert per table. It reports whether every insert succeeded.
+func timedInserts(ctx context.Context, run *results.Run, backend string, client interface{}, tables []string, data []Record) bool {
+	ok := true
+	for _, table := range tables {
+		if ctx.Err() != nil {
+			return false
+		}
+		watch := server.Watch(ctx, client, server.Report(run, backend, table))
+		latencies, errs := TimedInsert(ctx, client, table, data)
+		for _, err := range errs {
+			fmt.Printf("Error inserting data into %s %s: %v\n", backend, table, err)
+		}
+		r := results.Iterations(backend, table, "single-threaded insert", latencies)
+		r.Rows, _ = dataset.Rows(table)
+		r.Server = watch.Stop()
+		run.Add(r, errs...)
+		ok = ok && len(errs) == 0
 	}
+	return ok
+}
 
-	// Add the bars to the plot
-	p.Add(bars)
-
-	// Set the labels and title
-	p.Y.Label.Text = "Time (seconds)"
-	p.X.Label.Text = "Insert Operations"
-	p.Title.Text = "Time taken for insert operations"
-
-	// Set labels for X axis
-	p.NominalX(labels...)
-
-	// Save the plot to a file
-	if err := p.Save(10*vg.Inch, 6*vg.Inch, "./plots/plot_create.png"); err != nil {
+func plotGraph(run *results.Run) {
+	var totals []results.Result
+	for _, r := range run.Results {
+		if r.Table == "all" {
+			totals = append(totals, r)
+		}
+	}
+	operation := func(r results.Result) string { return r.Operation }
+	if err := plot.Bars("create", "Time taken for insert operations", "Insert operations", totals, operation, plot.Duration); err != nil {
+		fmt.Println("Error saving plot:", err)
+	}
+	table := func(r results.Result) string { return dataset.Label(r.Table) }
+	if err := plot.Distributions("create", "Single-threaded insert", run.Results, table); err != nil {
 		fmt.Println("Error saving plot:", err)
 	}
 }
@@ -0,0 +1,110 @@
+package dataset
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Record is one row of the tables and collections being benchmarked. In
+// MongoDB its fields keep the column names of the imported dataset.
+type Record struct {
+	Name       string  `bson:"Name"`
+	School     string  `bson:"School"`
+	Job        string  `bson:"Job"`
+	Department string  `bson:"Department"`
+	Earnings   float64 `bson:"Earnings"`
+	Year       int     `bson:"Year"`
+}
+
+// Load reads the seed rows of a table from ./dataset/<table>.csv
+func Load(table string) ([]Record, error) {
+	file, err := os.Open("./dataset/" + table + ".csv")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+
+	// Skip the header row
+	if _, err := reader.Read(); err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(row) != 6 {
+			return nil, fmt.Errorf("%s: expected 6 columns, got %d", table, len(row))
+		}
+
+		// Some rows have no Earnings, they are imported as 0
+		var earnings float64
+		if row[4] != "" {
+			earnings, err = strconv.ParseFloat(row[4], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid Earnings %q: %v", table, row[4], err)
+			}
+		}
+		year, err := strconv.Atoi(row[5])
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid Year %q: %v", table, row[5], err)
+		}
+
+		records = append(records, Record{
+			Name:       row[0],
+			School:     row[1],
+			Job:        row[2],
+			Department: row[3],
+			Earnings:   earnings,
+			Year:       year,
+		})
+	}
+
+	return records, nil
+}
+
+var (
+	rowsMu sync.Mutex
+	rows   = make(map[string]int)
+)
+
+// Rows returns the number of rows a table is seeded with, counted from its CSV
+func Rows(table string) (int, error) {
+	rowsMu.Lock()
+	defer rowsMu.Unlock()
+
+	if n, ok := rows[table]; ok {
+		return n, nil
+	}
+	records, err := Load(table)
+	if err != nil {
+		return 0, err
+	}
+	rows[table] = len(records)
+	return len(records), nil
+}
+
+// Label names a table together with its size, e.g. "table2 (5k rows)"
+func Label(table string) string {
+	n, err := Rows(table)
+	if err != nil {
+		return table
+	}
+	if n >= 1000 {
+		thousands := math.Round(float64(n)/100) / 10
+		return fmt.Sprintf("%s (%sk rows)", table, strconv.FormatFloat(thousands, 'f', -1, 64))
+	}
+	return fmt.Sprintf("%s (%d rows)", table, n)
+}
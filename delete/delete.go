@@ -5,298 +5,478 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"benchmarkDB/bench"
 	"benchmarkDB/create"
-
-	"gonum.org/v1/plot"
-	"gonum.org/v1/plot/plotter"
-	"gonum.org/v1/plot/plotutil"
-	"gonum.org/v1/plot/vg"
+	"benchmarkDB/dataset"
+	"benchmarkDB/explain"
+	"benchmarkDB/fixture"
+	"benchmarkDB/report/plot"
+	"benchmarkDB/results"
+	"benchmarkDB/runctx"
+	"benchmarkDB/server"
 )
 
-func Delete() {
-	// Dummy data to be inserted
-	data1 := []create.Record{
-		{Name: "Rajesh Kumar", School: "Delhi Public School", Job: "Software Engineer", Department: "Engineering", Earnings: 50000, Year: 2023},
-		{Name: "Priya Patel", School: "Kendriya Vidyalaya", Job: "Data Analyst", Department: "Analytics", Earnings: 45000, Year: 2023},
-		{Name: "Amit Singh", School: "St. Xavier's School", Job: "Marketing Manager", Department: "Marketing", Earnings: 60000, Year: 2023},
-		{Name: "Sunita Gupta", School: "Holy Cross School", Job: "HR Executive", Department: "Human Resources", Earnings: 48000, Year: 2023},
-	}
+// Options of the delete benchmark
+type Options struct {
+	BulkRows int // rows removed by one bulk delete
+	Workers  int // concurrent clients of the multi-threaded delete
+}
 
-	data2 := []create.Record{
-		{Name: "Vikas Sharma", School: "DPS School", Job: "Software Developer", Department: "IT", Earnings: 55000, Year: 2023},
-		{Name: "Anita Verma", School: "KV School", Job: "Data Scientist", Department: "Analytics", Earnings: 48000, Year: 2023},
-		{Name: "Sachin Gupta", School: "St. Xavier's School", Job: "Sales Manager", Department: "Sales", Earnings: 62000, Year: 2023},
-		{Name: "Meena Singh", School: "Holy Family School", Job: "HR Manager", Department: "Human Resources", Earnings: 52000, Year: 2023},
-		{Name: "Rajesh Verma", School: "DPS School", Job: "Software Engineer", Department: "IT", Earnings: 55000, Year: 2023},
-		{Name: "Anjali Tiwari", School: "KV School", Job: "Data Analyst", Department: "Analytics", Earnings: 49000, Year: 2023},
-		{Name: "Rahul Singh", School: "St. Xavier's School", Job: "Marketing Manager", Department: "Marketing", Earnings: 61000, Year: 2023},
-		{Name: "Sunita Sharma", School: "Holy Family School", Job: "HR Executive", Department: "Human Resources", Earnings: 53000, Year: 2023},
-		{Name: "Nitin Kumar", School: "DPS School", Job: "Software Developer", Department: "IT", Earnings: 56000, Year: 2023},
-		{Name: "Priyanka Singh", School: "KV School", Job: "Data Scientist", Department: "Analytics", Earnings: 47000, Year: 2023},
-		{Name: "Amit Sharma", School: "St. Xavier's School", Job: "Sales Manager", Department: "Sales", Earnings: 63000, Year: 2023},
-		{Name: "Pooja Verma", School: "Holy Family School", Job: "HR Manager", Department: "Human Resources", Earnings: 54000, Year: 2023},
-		{Name: "Ajay Singh", School: "DPS School", Job: "Software Developer", Department: "IT", Earnings: 57000, Year: 2023},
-		{Name: "Deepika Patel", School: "KV School", Job: "Data Analyst", Department: "Analytics", Earnings: 46000, Year: 2023},
-		{Name: "Vikram Singh", School: "St. Xavier's School", Job: "Marketing Manager", Department: "Marketing", Earnings: 60000, Year: 2023},
-		{Name: "Monica Sharma", School: "Holy Family School", Job: "HR Executive", Department: "Human Resources", Earnings: 51000, Year: 2023},
-		{Name: "Sunil Kumar", School: "DPS School", Job: "Software Developer", Department: "IT", Earnings: 58000, Year: 2023},
-		{Name: "Neetu Singh", School: "KV School", Job: "Data Scientist", Department: "Analytics", Earnings: 49000, Year: 2023},
-		{Name: "Rohit Verma", School: "St. Xavier's School", Job: "Sales Manager", Department: "Sales", Earnings: 64000, Year: 2023},
-		{Name: "Sapna Sharma", School: "Holy Family School", Job: "HR Manager", Department: "Human Resources", Earnings: 55000, Year: 2023},
+// Default options, set from the command line
+var Default = Options{BulkRows: 100, Workers: 4}
+
+// Validate checks that every delete removes rows and has a client to run on
+func (o Options) Validate() error {
+	if o.BulkRows < 1 || o.Workers < 1 {
+		return errors.New("bulk rows and workers must be at least 1")
 	}
+	return nil
+}
 
-	tables := []string{"table1", "table2", "table3", "table4"} // Representing MongoDB collections or MySQL tables
+// Number of times each single-threaded delete is repeated
+const iterations = 20
 
-	var mongoClient *mongo.Client
-	var mysqlDB *sql.DB
-	var err error
+// Rows matching the filter of one delete by filter
+const filterRows = 10
 
-	// Initialize MongoDB client
-	mongoClient, err = initMongoClient()
-	if err != nil {
-		fmt.Println("Error initializing MongoDB client:", err)
-		return
-	}
-	defer mongoClient.Disconnect(context.Background())
+// Rows removed by each worker of the multi-threaded delete
+const rowsPerWorker = 25
 
-	// Initialize MySQL client
-	mysqlDB, err = initMySQLClient()
-	if err != nil {
-		fmt.Println("Error initializing MySQL client:", err)
-		return
-	}
-	defer mysqlDB.Close()
+// kind of delete, i.e. how the rows to remove are selected
+type kind int
 
-	// Collect time taken for each operation
-	var mongoTimes, mysqlTimes []float64
+const (
+	byKey    kind = iota // one row by its key, Name and Year
+	byFilter             // every row of a Department, DeleteMany vs DELETE ... WHERE
+	bulk                 // many rows by key in one request, BulkWrite vs DELETE ... IN
+)
 
-	// Reinsert records before deletion for single-threaded operations
-	fmt.Println("Creating records for single-threaded delete")
-	start := time.Now()
-	err = create.SingleThreadedInsert(mongoClient, tables, data1)
-	if err != nil {
-		fmt.Println("Error reinserting data into MongoDB:", err)
-		return
-	}
-	mongoTimes = append(mongoTimes, time.Since(start).Seconds())
+// variant of the single-threaded delete, each removing its own fixture rows
+type variant struct {
+	kind      kind
+	operation string // recorded in the results
+	label     string // bar label in the charts
+	rows      int    // rows removed by one delete
+}
 
-	start = time.Now()
-	err = create.SingleThreadedInsert(mysqlDB, tables, data1)
-	if err != nil {
-		fmt.Println("Error reinserting data into MySQL:", err)
-		return
+func variants(opts Options) []variant {
+	return []variant{
+		{byKey, "delete by key", "by key (1 row)", 1},
+		{byFilter, "delete by filter", fmt.Sprintf("by filter (%d rows)", filterRows), filterRows},
+		{bulk, "bulk delete", fmt.Sprintf("bulk (%d rows)", opts.BulkRows), opts.BulkRows},
 	}
-	mysqlTimes = append(mysqlTimes, time.Since(start).Seconds())
+}
 
-	// Single Threaded Delete
-	fmt.Println("************Performing single-threaded deletes***************")
+func Delete() {
+	opts := Default
+	tables := []string{"table1", "table2", "table3", "table4"} // Representing MongoDB collections or MySQL tables
+
+	// Connected to both backends, starting from the seed data
+	ctx, b := bench.Start(tables)
+	mongoClient, mysqlDB := b.Mongo, b.MySQL
+
+	backends := []struct {
+		name   string
+		client interface{}
+	}{{"MongoDB", mongoClient}, {"MySQL", mysqlDB}}
+
+	// Fixture phase, not timed: insert the rows every delete below removes
+	fmt.Println("************Creating records to delete***************")
+	start := time.Now()
+	singleThreaded := make(map[string][][][]create.Record) // per table, per variant, per iteration
+	multiThreaded := make(map[string][][]create.Record)    // per table, per worker
 	for _, table := range tables {
-		start = time.Now()
-		err = singleThreadedDelete(mongoClient, table, data1)
-		if err != nil {
-			fmt.Printf("Error deleting data from MongoDB collection %s: %v\n", table, err)
-		} else {
-			fmt.Printf("Single-threaded delete successful from MongoDB collection %s\n", table)
-			mongoTimes = append(mongoTimes, time.Since(start).Seconds())
+		var records []create.Record
+		for i, v := range variants(opts) {
+			batches := fixtures(fmt.Sprintf("v%d", i), iterations, v.rows)
+			singleThreaded[table] = append(singleThreaded[table], batches)
+			records = append(records, flatten(batches)...)
 		}
-
-		start = time.Now()
-		err = singleThreadedDelete(mysqlDB, table, data1)
-		if err != nil {
-			fmt.Printf("Error deleting data from MySQL table %s: %v\n", table, err)
-		} else {
-			fmt.Printf("Single-threaded delete successful from MySQL table %s\n", table)
-			mysqlTimes = append(mysqlTimes, time.Since(start).Seconds())
+		multiThreaded[table] = fixtures("mt", opts.Workers, rowsPerWorker)
+		records = append(records, flatten(multiThreaded[table])...)
+
+		for _, backend := range backends {
+			// Without the reset, rows left by a run that did not finish
+			// would be deleted too
+			var err error
+			if !fixture.Enabled {
+				err = deleteRows(ctx, backend.client, table)
+			}
+			if err == nil {
+				err = fixture.Insert(ctx, backend.client, table, records)
+			}
+			if err != nil {
+				bench.Fail(fmt.Sprintf("Error creating records to delete in %s %s: %v", backend.name, table, err))
+			}
 		}
 	}
-	fmt.Println("*************************************************************")
+	fmt.Printf("Created the records in %v (not part of the results)\n", time.Since(start).Round(time.Millisecond))
 
-	// Reinsert records before deletion for multi-threaded operations
-	fmt.Println("Recreating records for multi-threaded delete")
-	start = time.Now()
-	err = create.SingleThreadedInsert(mongoClient, tables, data2)
-	if err != nil {
-		fmt.Println("Error reinserting data into MongoDB:", err)
-		return
-	}
-	mongoTimes = append(mongoTimes, time.Since(start).Seconds())
+	// Collect time taken for each operation
+	run := results.NewRun("delete", results.Config{Workers: opts.Workers})
+	run.Environment.Servers = b.Servers()
 
-	start = time.Now()
-	err = create.SingleThreadedInsert(mysqlDB, tables, data2)
-	if err != nil {
-		fmt.Println("Error reinserting data into MySQL:", err)
-		return
+	// Single Threaded Delete, every variant against every table
+	fmt.Println("************Performing single-threaded deletes***************")
+	for i, v := range variants(opts) {
+		for _, table := range tables {
+			if ctx.Err() != nil {
+				break
+			}
+			for _, backend := range backends {
+				// Untimed, explaining a delete does not remove the rows
+				plan := explain.Run(ctx, backend.client, deleteQuery(table, v.kind, singleThreaded[table][i][0]))
+				watch := server.Watch(ctx, backend.client, server.Report(run, backend.name, table))
+				latencies, errs := timedDelete(ctx, backend.client, table, v.kind, singleThreaded[table][i])
+				for _, err := range errs {
+					fmt.Printf("Error deleting %s from %s %s: %v\n", v.label, backend.name, table, err)
+				}
+				r := results.Iterations(backend.name, table, v.operation, latencies)
+				r.Rows, _ = dataset.Rows(table)
+				r.Server = watch.Stop()
+				r.Plan = plan
+				run.Add(r, errs...)
+				if len(latencies) > 0 {
+					fmt.Printf("Mean time of %s delete %s in %s: %v\n", backend.name, v.label, table, results.Micros(r.Latency.Mean))
+				}
+			}
+		}
 	}
-	mysqlTimes = append(mysqlTimes, time.Since(start).Seconds())
+	fmt.Println("*************************************************************")
 
-	// Multi Threaded Delete
-	fmt.Println("************Performing multi-threaded deletes***************")
+	// Multi Threaded Delete, each worker removing its own rows by key
+	fmt.Printf("************Performing multi-threaded deletes (%d workers)***************\n", opts.Workers)
 	for _, table := range tables {
-		start = time.Now()
-		err = multiThreadedDelete(mongoClient, table, data2)
-		if err != nil {
-			fmt.Printf("Error deleting data from multi-threaded MongoDB collection %s: %v\n", table, err)
-		} else {
-			fmt.Printf("Multi-threaded delete successful from MongoDB collection %s\n", table)
-			mongoTimes = append(mongoTimes, time.Since(start).Seconds())
+		if ctx.Err() != nil {
+			break
 		}
-
-		start = time.Now()
-		err = multiThreadedDelete(mysqlDB, table, data2)
-		if err != nil {
-			fmt.Printf("Error deleting data from multi-threaded MySQL table %s: %v\n", table, err)
-		} else {
-			fmt.Printf("Multi-threaded delete successful from MySQL table %s\n", table)
-			mysqlTimes = append(mysqlTimes, time.Since(start).Seconds())
+		for _, backend := range backends {
+			name := backend.name
+			var errs []error
+			var mu sync.Mutex
+			failed := func(err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				errs = append(errs, err)
+			}
+			var pending sync.WaitGroup
+			plan := explain.Run(ctx, backend.client, deleteQuery(table, byKey, multiThreaded[table][0][:1]))
+			watch := server.Watch(ctx, backend.client, server.Report(run, backend.name, table))
+			start := time.Now()
+			deleted := multiThreadedDelete(ctx, backend.client, table, multiThreaded[table], &pending, failed)
+			pending.Wait()
+			elapsed := time.Since(start)
+			for _, err := range errs {
+				fmt.Printf("Error deleting data from multi-threaded %s %s: %v\n", name, table, err)
+			}
+			r := results.Timing(name, table, "multi-threaded delete by key", elapsed, int(deleted.Load()))
+			r.Workers = opts.Workers
+			r.Rows, _ = dataset.Rows(table)
+			r.Server = watch.Stop()
+			r.Plan = plan
+			run.Add(r, errs...)
+			fmt.Printf("Time taken for multi-threaded %s delete in %s: %v\n", name, table, elapsed)
 		}
 	}
 	fmt.Println("************************************************************")
 
 	// Plotting the graph
-	plotGraph(mongoTimes, mysqlTimes, tables)
-
-	if err != nil {
-		fmt.Println("Program completed with errors")
-		os.Exit(1)
-	}
-
-	fmt.Println("Program completed successfully")
-	os.Exit(0)
+	run.Finished = time.Now()
+	plotGraph(run, tables)
+
+	b.After(func() error {
+		// Without the reset the tables are not restored, the rows the
+		// workers did not get to are deleted instead
+		if fixture.Enabled {
+			return nil
+		}
+		var errs []error
+		for _, table := range tables {
+			for _, backend := range backends {
+				if err := deleteRows(context.Background(), backend.client, table); err != nil {
+					errs = append(errs, fmt.Errorf("deleting the records left in %s %s: %v", backend.name, table, err))
+				}
+			}
+		}
+		return errors.Join(errs...)
+	})
+	b.Finish(run)
 }
 
-// Plot a graph comparing the time taken for MongoDB and MySQL operations
-func plotGraph(mongoTimes, mysqlTimes []float64, tables []string) {
-	// Create a new plot
-	p := plot.New()
-
-	// Create new bar chart
-	barWidth := vg.Points(50)
-	barsMongo, err := plotter.NewBarChart(plotter.Values(mongoTimes), barWidth)
-	if err != nil {
-		fmt.Println("Error creating bar chart for MongoDB:", err)
-		os.Exit(1)
+// Plot the mean time of each delete variant per table, so a bulk delete of
+// many rows is labelled with its row count next to the delete of one row,
+// and the time taken by the multi-threaded deletes
+func plotGraph(run *results.Run, tables []string) {
+	labels := make(map[string]string)
+	for _, v := range variants(Default) {
+		labels[v.operation] = v.label
 	}
-	barsMongo.LineStyle.Width = vg.Length(0)
-	barsMongo.Color = plotutil.Color(0)
-	barsMySQL, err := plotter.NewBarChart(plotter.Values(mysqlTimes), barWidth)
-	if err != nil {
-		fmt.Println("Error creating bar chart for MySQL:", err)
-		os.Exit(1)
+	variant := func(r results.Result) string { return labels[r.Operation] }
+	for _, table := range tables {
+		var rs []results.Result
+		for _, r := range run.Results {
+			if r.Table == table && labels[r.Operation] != "" {
+				rs = append(rs, r)
+			}
+		}
+		if len(rs) == 0 {
+			continue
+		}
+		if err := plot.Bars("delete_"+table, "Time per delete in "+dataset.Label(table), "Delete", rs, variant, plot.Mean); err != nil {
+			fmt.Println("Error saving plot:", err)
+		}
 	}
-	barsMySQL.LineStyle.Width = vg.Length(0)
-	barsMySQL.Color = plotutil.Color(1)
 
-	// Add bars to the plot
-	p.Add(barsMongo, barsMySQL)
-
-	// Set labels and title
-	p.Title.Text = "Operation Timings"
-	p.X.Label.Text = "Time (ms)"
-	p.Y.Label.Text = "Frequency"
-	if err := p.Save(6*vg.Inch, 4*vg.Inch, "timings.png"); err != nil {
+	var multi, single []results.Result
+	for _, r := range run.Results {
+		switch r.Operation {
+		case "multi-threaded delete by key":
+			multi = append(multi, r)
+		case "delete by key":
+			single = append(single, r)
+		}
+	}
+	table := func(r results.Result) string { return dataset.Label(r.Table) }
+	title := fmt.Sprintf("Time taken for multi-threaded deletes by key (%d workers)", run.Config.Workers)
+	if err := plot.Bars("delete_multi_threaded", title, "Table", multi, table, plot.Duration); err != nil {
 		fmt.Println("Error saving plot:", err)
 	}
-
-	// Save the plot to a PNG file
-	err = p.Save(6*vg.Inch, 4*vg.Inch, "./plots/plot_delete.png")
-	if err != nil {
+	if err := plot.Distributions("delete", "Delete by key", single, table); err != nil {
 		fmt.Println("Error saving plot:", err)
-		os.Exit(1)
 	}
-
-	fmt.Println("Plot saved as plot.png")
 }
 
-func initMongoClient() (*mongo.Client, error) {
-	clientOptions := options.Client().ApplyURI("mongodb://localhost:27017")
-	client, err := mongo.Connect(context.Background(), clientOptions)
-	if err != nil {
-		return nil, err
+// fixtures generates n batches of rows no other row shares: each row has its
+// own key (Name and Year) and each batch its own Department
+func fixtures(tag string, n, rows int) [][]create.Record {
+	batches := make([][]create.Record, n)
+	for i := range batches {
+		department := fmt.Sprintf("Delete benchmark %s-%d", tag, i)
+		for j := 0; j < rows; j++ {
+			batches[i] = append(batches[i], create.Record{
+				Name:       fmt.Sprintf("Delete fixture %s-%d-%d", tag, i, j),
+				School:     fixtureSchool,
+				Job:        "Fixture",
+				Department: department,
+				Earnings:   50000,
+				Year:       1900,
+			})
+		}
 	}
+	return batches
+}
 
-	err = client.Ping(context.Background(), nil)
-	if err != nil {
-		return nil, err
-	}
+// School of the rows created to be deleted, which no row of the dataset has
+const fixtureSchool = "Delete benchmark"
 
-	return client, nil
+// deleteRows deletes the rows fixtures created in a table. It runs even after
+// the run was stopped, bounded only by the operation timeout.
+func deleteRows(ctx context.Context, client interface{}, table string) error {
+	return runctx.Do(ctx, func(ctx context.Context) error {
+		switch c := client.(type) {
+		case *mongo.Client:
+			_, err := c.Database("MONGODB_DATABASE").Collection(table).DeleteMany(ctx, bson.M{"School": fixtureSchool})
+			return err
+		case *sql.DB:
+			_, err := c.ExecContext(ctx, "DELETE FROM "+table+" WHERE School = ?", fixtureSchool)
+			return err
+		default:
+			return errors.New("unsupported client type")
+		}
+	})
 }
 
-func initMySQLClient() (*sql.DB, error) {
-	db, err := sql.Open("mysql", ":SQL_PASStcp(localhost:3306)/MYSQL_DATABASE")
-	if err != nil {
-		return nil, err
-	}
-
-	err = db.Ping()
-	if err != nil {
-		return nil, err
+func flatten(batches [][]create.Record) []create.Record {
+	var records []create.Record
+	for _, batch := range batches {
+		records = append(records, batch...)
 	}
-
-	return db, nil
+	return records
 }
 
-func singleThreadedDelete(client interface{}, table string, data []create.Record) error {
+// deleter removes one batch of rows and returns how many rows it removed
+type deleter func(ctx context.Context, batch []create.Record) (int64, error)
+
+// newDeleter returns the delete of a kind for batches of the given size. On
+// MySQL the statement is prepared once, release closes it.
+func newDeleter(ctx context.Context, client interface{}, table string, k kind, rows int) (remove deleter, release func(), err error) {
 	switch c := client.(type) {
 	case *mongo.Client:
 		mongoClient := c
 		collection := mongoClient.Database("MONGODB_DATABASE").Collection(table)
-		for _, record := range data {
-			filter := bson.M{"Name": record.Name, "Year": record.Year} // Assuming Name and Year as unique identifiers
-			_, err := collection.DeleteOne(context.Background(), filter)
+		remove = func(ctx context.Context, batch []create.Record) (int64, error) {
+			var result *mongo.DeleteResult
+			var err error
+			switch k {
+			case byKey:
+				result, err = collection.DeleteOne(ctx, mongoFilter(k, batch[0]))
+			case byFilter:
+				result, err = collection.DeleteMany(ctx, mongoFilter(k, batch[0]))
+			default:
+				models := make([]mongo.WriteModel, len(batch))
+				for i, record := range batch {
+					models[i] = mongo.NewDeleteOneModel().SetFilter(mongoFilter(k, record))
+				}
+				written, err := collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+				if err != nil {
+					return 0, err
+				}
+				return written.DeletedCount, nil
+			}
 			if err != nil {
-				return err
+				return 0, err
 			}
+			return result.DeletedCount, nil
 		}
-		return nil
+		return remove, func() {}, nil
 	case *sql.DB:
 		mysqlDB := c
-		query := "DELETE FROM " + table + " WHERE Name = ? AND Year = ?"
-		stmt, err := mysqlDB.Prepare(query)
-		if err != nil {
+		query := mysqlDelete(table, k, rows)
+		var stmt *sql.Stmt
+		err := runctx.Do(ctx, func(ctx context.Context) error {
+			var err error
+			stmt, err = mysqlDB.PrepareContext(ctx, query)
 			return err
+		})
+		if err != nil {
+			return nil, nil, err
 		}
-		defer stmt.Close()
-		for _, record := range data {
-			_, err := stmt.Exec(record.Name, record.Year)
+		remove = func(ctx context.Context, batch []create.Record) (int64, error) {
+			result, err := stmt.ExecContext(ctx, mysqlArgs(k, batch)...)
 			if err != nil {
-				return err
+				return 0, err
 			}
+			return result.RowsAffected()
 		}
-		return nil
+		return remove, func() { stmt.Close() }, nil
 	default:
-		return errors.New("unsupported client type")
+		return nil, nil, errors.New("unsupported client type")
 	}
 }
 
-func multiThreadedDelete(client interface{}, table string, data []create.Record) error {
-	switch c := client.(type) {
-	case *mongo.Client:
-		mongoClient := c
-		go func() {
-			err := singleThreadedDelete(mongoClient, table, data)
-			if err != nil {
-				fmt.Printf("Error deleting data from MongoDB collection %s: %v\n", table, err)
+// mongoFilter selects the rows of a batch a MongoDB delete of a kind removes
+// with record, the first of the batch when deleting by filter
+func mongoFilter(k kind, record create.Record) bson.M {
+	if k == byFilter {
+		return bson.M{"Department": record.Department}
+	}
+	return bson.M{"Name": record.Name, "Year": record.Year}
+}
+
+// mysqlDelete is the MySQL delete of a kind for batches of the given size
+func mysqlDelete(table string, k kind, rows int) string {
+	switch k {
+	case byKey:
+		return "DELETE FROM " + table + " WHERE Name = ? AND Year = ?"
+	case byFilter:
+		return "DELETE FROM " + table + " WHERE Department = ?"
+	default:
+		return "DELETE FROM " + table + " WHERE (Name, Year) IN (" + strings.TrimSuffix(strings.Repeat("(?, ?), ", rows), ", ") + ")"
+	}
+}
+
+// mysqlArgs are the arguments of the MySQL delete of a batch
+func mysqlArgs(k kind, batch []create.Record) []interface{} {
+	var args []interface{}
+	if k == byFilter {
+		args = append(args, batch[0].Department)
+	} else {
+		for _, record := range batch {
+			args = append(args, record.Name, record.Year)
+		}
+	}
+	return args
+}
+
+// deleteQuery is the delete of a batch as explain runs it. A MongoDB bulk
+// write cannot be explained, its first delete stands for it.
+func deleteQuery(table string, k kind, batch []create.Record) explain.Query {
+	limit := 1
+	if k == byFilter {
+		limit = 0
+	}
+	return explain.Query{
+		SQL:   mysqlDelete(table, k, len(batch)),
+		Args:  mysqlArgs(k, batch),
+		Mongo: explain.Delete(table, mongoFilter(k, batch[0]), limit),
+	}
+}
+
+// timedDelete removes each batch with one delete and returns the latency of
+// each successful delete in seconds and the errors of the failed ones. A
+// delete that removed fewer rows than its batch holds failed. It returns
+// early when the run is stopped.
+func timedDelete(ctx context.Context, client interface{}, table string, k kind, batches [][]create.Record) ([]float64, []error) {
+	latencies := make([]float64, 0, len(batches))
+	if len(batches) == 0 {
+		return latencies, nil
+	}
+	remove, release, err := newDeleter(ctx, client, table, k, len(batches[0]))
+	if runctx.Stopped(err) {
+		return latencies, nil
+	}
+	if err != nil {
+		errs := make([]error, len(batches))
+		for i := range errs {
+			errs[i] = err
+		}
+		return latencies, errs
+	}
+	defer release()
+
+	var errs []error
+	for _, batch := range batches {
+		var deleted int64
+		start := time.Now()
+		err := runctx.Do(ctx, func(ctx context.Context) error {
+			var err error
+			deleted, err = remove(ctx, batch)
+			return err
+		})
+		elapsed := time.Since(start)
+		if runctx.Stopped(err) {
+			break
+		}
+		if err == nil && deleted != int64(len(batch)) {
+			err = fmt.Errorf("deleted %d of %d rows", deleted, len(batch))
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		latencies = append(latencies, elapsed.Seconds())
+	}
+	return latencies, errs
+}
+
+// multiThreadedDelete starts one worker per slice of rows, each deleting its
+// rows by key. pending is done once they finished and failed is called with
+// each of their errors. The returned count holds the rows deleted so far.
+func multiThreadedDelete(ctx context.Context, client interface{}, table string, perWorker [][]create.Record, pending *sync.WaitGroup, failed func(error)) *atomic.Int64 {
+	var deleted atomic.Int64
+	for _, records := range perWorker {
+		pending.Add(1)
+		go func(records []create.Record) {
+			defer pending.Done()
+			keys := make([][]create.Record, len(records))
+			for i := range records {
+				keys[i] = records[i : i+1]
 			}
-		}()
-		return nil
-	case *sql.DB:
-		mysqlDB := c
-		go func() {
-			err := singleThreadedDelete(mysqlDB, table, data)
-			if err != nil {
-				fmt.Printf("Error deleting data from MySQL table %s: %v\n", table, err)
+			latencies, errs := timedDelete(ctx, client, table, byKey, keys)
+			deleted.Add(int64(len(latencies)))
+			for _, err := range errs {
+				failed(err)
 			}
-		}()
-		return nil
-	default:
-		return errors.New("unsupported client type")
+		}(records)
 	}
+	return &deleted
 }
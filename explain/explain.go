@@ -0,0 +1,217 @@
+// Package explain asks each backend how it runs the queries of a workload:
+// EXPLAIN FORMAT=JSON on MySQL and explain with executionStats on MongoDB.
+// The plans are summarised as the access method, the index used and the
+// rows or documents examined, and stored with the results.
+package explain
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"benchmarkDB/results"
+	"benchmarkDB/runctx"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Enabled explains every workload query before it is timed, set from the
+// command line
+var Enabled = false
+
+// Query is one query of a workload in the form each backend runs it
+type Query struct {
+	SQL   string        // MySQL statement
+	Args  []interface{} // arguments of SQL
+	Mongo bson.D        // MongoDB command, see Find, Update and Delete
+}
+
+// Find is the MongoDB command of a find. sort and limit are left out when nil
+// and 0.
+func Find(collection string, filter, sort interface{}, limit int64) bson.D {
+	command := bson.D{{Key: "find", Value: collection}, {Key: "filter", Value: filter}}
+	if sort != nil {
+		command = append(command, bson.E{Key: "sort", Value: sort})
+	}
+	if limit > 0 {
+		command = append(command, bson.E{Key: "limit", Value: limit})
+	}
+	return command
+}
+
+// Update is the MongoDB command of an update, of every matching document
+// when multi is set
+func Update(collection string, filter, update interface{}, multi, upsert bool) bson.D {
+	statement := bson.D{{Key: "q", Value: filter}, {Key: "u", Value: update}, {Key: "multi", Value: multi}, {Key: "upsert", Value: upsert}}
+	return bson.D{{Key: "update", Value: collection}, {Key: "updates", Value: bson.A{statement}}}
+}
+
+// Delete is the MongoDB command of a delete, of one matching document when
+// limit is 1 and of all of them when it is 0
+func Delete(collection string, filter interface{}, limit int) bson.D {
+	statement := bson.D{{Key: "q", Value: filter}, {Key: "limit", Value: limit}}
+	return bson.D{{Key: "delete", Value: collection}, {Key: "deletes", Value: bson.A{statement}}}
+}
+
+// Run explains the query on the backend of client. It returns nil when
+// explaining is off and a plan with the error when the query could not be
+// explained, which does not fail the run.
+func Run(ctx context.Context, client interface{}, q Query) *results.Plan {
+	if !Enabled {
+		return nil
+	}
+	var plan *results.Plan
+	err := runctx.Do(ctx, func(ctx context.Context) error {
+		var err error
+		switch c := client.(type) {
+		case *mongo.Client:
+			plan, err = explainMongo(ctx, c, q.Mongo)
+		case *sql.DB:
+			plan, err = explainMySQL(ctx, c, q.SQL, q.Args)
+		default:
+			err = errors.New("unsupported client type")
+		}
+		return err
+	})
+	if err != nil {
+		return &results.Plan{Error: err.Error()}
+	}
+	return plan
+}
+
+func explainMySQL(ctx context.Context, mysqlDB *sql.DB, query string, args []interface{}) (*results.Plan, error) {
+	var raw string
+	if err := mysqlDB.QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+query, args...).Scan(&raw); err != nil {
+		return nil, err
+	}
+	var tree map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &tree); err != nil {
+		return nil, err
+	}
+
+	plan := &results.Plan{Query: query, Estimated: true, Raw: json.RawMessage(raw)}
+	// The first table the query block reads, possibly nested in an
+	// ordering_operation or similar
+	if table := find(tree, func(m map[string]interface{}) bool { return m["access_type"] != nil }); table != nil {
+		plan.Access, _ = table["access_type"].(string)
+		plan.Index, _ = table["key"].(string)
+		plan.Examined = number(table["rows_examined_per_scan"])
+		plan.Returned = number(table["rows_produced_per_join"])
+	}
+	return plan, nil
+}
+
+func explainMongo(ctx context.Context, mongoClient *mongo.Client, command bson.D) (*results.Plan, error) {
+	var doc bson.M
+	explainCommand := bson.D{{Key: "explain", Value: command}, {Key: "verbosity", Value: "executionStats"}}
+	if err := mongoClient.Database("MONGODB_DATABASE").RunCommand(ctx, explainCommand).Decode(&doc); err != nil {
+		return nil, err
+	}
+	raw, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]interface{}
+	if err := json.Unmarshal(raw, &tree); err != nil {
+		return nil, err
+	}
+	query, err := bson.MarshalExtJSON(command, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &results.Plan{Query: string(query), Raw: raw}
+	if planner, ok := tree["queryPlanner"].(map[string]interface{}); ok {
+		plan.Access, plan.Index = stages(planner["winningPlan"])
+	}
+	if stats, ok := tree["executionStats"].(map[string]interface{}); ok {
+		plan.Examined = number(stats["totalDocsExamined"])
+		plan.KeysExamined = number(stats["totalKeysExamined"])
+		plan.Returned = number(stats["nReturned"])
+	}
+	return plan, nil
+}
+
+// stages walks a MongoDB plan down to the stage that reads the collection,
+// e.g. COLLSCAN or IXSCAN, and returns it with the index it uses
+func stages(node interface{}) (access, index string) {
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		return "", ""
+	}
+	// Plans of the slot based engine wrap the classic plan in queryPlan
+	if inner, ok := m["queryPlan"]; ok {
+		return stages(inner)
+	}
+	access, _ = m["stage"].(string)
+	index, _ = m["indexName"].(string)
+	var children []interface{}
+	if child, ok := m["inputStage"]; ok {
+		children = append(children, child)
+	}
+	if list, ok := m["inputStages"].([]interface{}); ok {
+		children = append(children, list...)
+	}
+	for _, child := range children {
+		childAccess, childIndex := stages(child)
+		if childAccess != "" {
+			access = childAccess
+		}
+		if childIndex != "" {
+			index = childIndex
+		}
+	}
+	return access, index
+}
+
+// find returns the first object in a JSON tree, depth first, that matches.
+// Arrays are walked in order and the members of an object by name, so the
+// same plan always gives the same object, e.g. the first table of a
+// nested_loop.
+func find(node interface{}, match func(map[string]interface{}) bool) map[string]interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if match(n) {
+			return n
+		}
+		keys := make([]string, 0, len(n))
+		for key := range n {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if found := find(n[key], match); found != nil {
+				return found
+			}
+		}
+	case []interface{}:
+		for _, child := range n {
+			if found := find(child, match); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// number reads a count from JSON, where MySQL writes some as strings and
+// MongoDB extended JSON wraps 64-bit integers, e.g. {"$numberLong": "12"}
+func number(v interface{}) int64 {
+	switch n := v.(type) {
+	case float64:
+		return int64(n)
+	case string:
+		var i int64
+		fmt.Sscan(n, &i)
+		return i
+	case map[string]interface{}:
+		for _, wrapped := range n {
+			return number(wrapped)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,28 @@
+package explain
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFindIsDeterministic(t *testing.T) {
+	// A join reads t1 first, an object holding several tables is walked by name
+	raw := `{"query_block": {
+		"select_id": 1,
+		"ordering_operation": {"table": {"table_name": "b", "access_type": "ref"}},
+		"nested_loop": [
+			{"table": {"table_name": "t1", "access_type": "ALL"}},
+			{"table": {"table_name": "t2", "access_type": "eq_ref"}}
+		]
+	}}`
+	var tree map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &tree); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		table := find(tree, func(m map[string]interface{}) bool { return m["access_type"] != nil })
+		if table == nil || table["table_name"] != "t1" {
+			t.Fatalf("found %v, want the first table of the nested loop", table)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Package fixture puts the benchmarked tables and collections into a known
+// state around a workload: every table is emptied and seeded again from
+// dataset/<table>.csv, so each run starts from identical data. The time this
+// takes is printed but never recorded in the results.
+package fixture
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"benchmarkDB/dataset"
+	"benchmarkDB/runctx"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Enabled resets the tables before and after every workload. Turn it off to
+// benchmark data that is maintained outside the tool.
+var Enabled = true
+
+// batchSize is the number of rows written by one insert while seeding
+const batchSize = 1000
+
+// Setup resets the tables on both backends before a workload runs
+func Setup(ctx context.Context, mongoClient *mongo.Client, mysqlDB *sql.DB, tables []string) error {
+	return resetAll(ctx, "Resetting tables to the seed data", mongoClient, mysqlDB, tables)
+}
+
+// Teardown restores the tables a workload changed. It runs even after the run
+// was stopped, bounded only by the operation timeout.
+func Teardown(mongoClient *mongo.Client, mysqlDB *sql.DB, tables []string) error {
+	return resetAll(context.Background(), "Restoring tables to the seed data", mongoClient, mysqlDB, tables)
+}
+
+func resetAll(ctx context.Context, title string, mongoClient *mongo.Client, mysqlDB *sql.DB, tables []string) error {
+	if !Enabled {
+		return nil
+	}
+	fmt.Printf("************%s***************\n", title)
+	start := time.Now()
+	for _, table := range tables {
+		records, err := dataset.Load(table)
+		if err != nil {
+			return err
+		}
+		if err := Reset(ctx, mongoClient, table, records); err != nil {
+			return fmt.Errorf("MongoDB %s: %v", table, err)
+		}
+		if err := Reset(ctx, mysqlDB, table, records); err != nil {
+			return fmt.Errorf("MySQL %s: %v", table, err)
+		}
+	}
+	fmt.Printf("Reset %d tables in %v (not part of the results)\n", len(tables), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// Reset empties a table and inserts the records into it
+func Reset(ctx context.Context, client interface{}, table string, records []dataset.Record) error {
+	switch c := client.(type) {
+	case *mongo.Client:
+		mongoClient := c
+		err := runctx.Do(ctx, func(ctx context.Context) error {
+			_, err := mongoClient.Database("MONGODB_DATABASE").Collection(table).DeleteMany(ctx, bson.M{})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	case *sql.DB:
+		mysqlDB := c
+		err := runctx.Do(ctx, func(ctx context.Context) error {
+			_, err := mysqlDB.ExecContext(ctx, "TRUNCATE TABLE "+table)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		return errors.New("unsupported client type")
+	}
+	return Insert(ctx, client, table, records)
+}
+
+// Insert adds the records to a table in batches. It is meant for fixtures
+// and is not timed.
+func Insert(ctx context.Context, client interface{}, table string, records []dataset.Record) error {
+	switch c := client.(type) {
+	case *mongo.Client:
+		mongoClient := c
+		collection := mongoClient.Database("MONGODB_DATABASE").Collection(table)
+		for start := 0; start < len(records); start += batchSize {
+			batch := records[start:min(start+batchSize, len(records))]
+			documents := make([]interface{}, len(batch))
+			for i, record := range batch {
+				documents[i] = record
+			}
+			err := runctx.Do(ctx, func(ctx context.Context) error {
+				_, err := collection.InsertMany(ctx, documents)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case *sql.DB:
+		mysqlDB := c
+		for start := 0; start < len(records); start += batchSize {
+			batch := records[start:min(start+batchSize, len(records))]
+			query := "INSERT INTO " + table + " (Name, School, Job, Department, Earnings, Year) VALUES " +
+				strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?), ", len(batch)), ", ")
+			args := make([]interface{}, 0, 6*len(batch))
+			for _, record := range batch {
+				args = append(args, record.Name, record.School, record.Job, record.Department, record.Earnings, record.Year)
+			}
+			err := runctx.Do(ctx, func(ctx context.Context) error {
+				_, err := mysqlDB.ExecContext(ctx, query, args...)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return errors.New("unsupported client type")
+	}
+}
+
+// CreateKeyed creates the table or collection name, empty, with a unique key
+// on Name and Year. The seeded tables have no such key since the dataset
+// repeats some Name and Year pairs, but upserts need one to find a row by.
+func CreateKeyed(ctx context.Context, client interface{}, name string) error {
+	switch c := client.(type) {
+	case *mongo.Client:
+		mongoClient := c
+		collection := mongoClient.Database("MONGODB_DATABASE").Collection(name)
+		return runctx.Do(ctx, func(ctx context.Context) error {
+			if err := collection.Drop(ctx); err != nil {
+				return err
+			}
+			_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "Name", Value: 1}, {Key: "Year", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			})
+			return err
+		})
+	case *sql.DB:
+		mysqlDB := c
+		return runctx.Do(ctx, func(ctx context.Context) error {
+			if _, err := mysqlDB.ExecContext(ctx, "DROP TABLE IF EXISTS "+name); err != nil {
+				return err
+			}
+			_, err := mysqlDB.ExecContext(ctx, "CREATE TABLE "+name+" (Name VARCHAR(255) NOT NULL, School VARCHAR(255), Job VARCHAR(255), "+
+				"Department VARCHAR(255), Earnings DOUBLE, Year INT NOT NULL, UNIQUE KEY name_year (Name, Year))")
+			return err
+		})
+	default:
+		return errors.New("unsupported client type")
+	}
+}
+
+// Drop removes a table or collection created with CreateKeyed
+func Drop(ctx context.Context, client interface{}, name string) error {
+	switch c := client.(type) {
+	case *mongo.Client:
+		mongoClient := c
+		return runctx.Do(ctx, func(ctx context.Context) error {
+			return mongoClient.Database("MONGODB_DATABASE").Collection(name).Drop(ctx)
+		})
+	case *sql.DB:
+		mysqlDB := c
+		return runctx.Do(ctx, func(ctx context.Context) error {
+			_, err := mysqlDB.ExecContext(ctx, "DROP TABLE IF EXISTS "+name)
+			return err
+		})
+	default:
+		return errors.New("unsupported client type")
+	}
+}
@@ -0,0 +1,344 @@
+// Package histogram records latencies in a High Dynamic Range histogram.
+//
+// The bucket layout is the one used by HdrHistogram, so histograms can be
+// exported in the HdrHistogram log format and read by its standard tools
+// (HistogramLogProcessor, HistogramLogAnalyzer, ...).
+package histogram
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Histogram counts values between lowest and highest with a fixed number of
+// significant decimal digits of precision. Histograms are not safe for
+// concurrent use: give each worker its own and Merge them at the end.
+type Histogram struct {
+	lowest  int64
+	highest int64
+	sigFigs int
+
+	unitMagnitude               int
+	subBucketHalfCountMagnitude int
+	subBucketCount              int
+	subBucketHalfCount          int
+	subBucketMask               int64
+	bucketCount                 int
+	leadingZeroCountBase        int
+
+	counts     []int64
+	totalCount int64
+	min        int64
+	max        int64
+}
+
+// New returns an empty histogram tracking values in [lowest, highest]
+// with sigFigs (1-5) significant digits.
+func New(lowest, highest int64, sigFigs int) (*Histogram, error) {
+	if lowest < 1 {
+		return nil, errors.New("lowest discernible value must be at least 1")
+	}
+	if highest < 2*lowest {
+		return nil, errors.New("highest trackable value must be at least twice the lowest")
+	}
+	if sigFigs < 1 || sigFigs > 5 {
+		return nil, errors.New("significant figures must be between 1 and 5")
+	}
+
+	largestValueWithSingleUnitResolution := 2 * int64(math.Pow10(sigFigs))
+	subBucketCountMagnitude := int(math.Ceil(math.Log2(float64(largestValueWithSingleUnitResolution))))
+	subBucketHalfCountMagnitude := subBucketCountMagnitude - 1
+	if subBucketHalfCountMagnitude < 0 {
+		subBucketHalfCountMagnitude = 0
+	}
+	unitMagnitude := int(math.Floor(math.Log2(float64(lowest))))
+	subBucketCount := 1 << (subBucketHalfCountMagnitude + 1)
+
+	// Number of buckets needed to cover highest, each doubling the range of the
+	// last, as in HdrHistogram's getBucketsNeededToCoverValue
+	smallestUntrackableValue := int64(subBucketCount) << unitMagnitude
+	bucketCount := 1
+	for smallestUntrackableValue <= highest {
+		if smallestUntrackableValue > math.MaxInt64/2 {
+			bucketCount++
+			break
+		}
+		smallestUntrackableValue <<= 1
+		bucketCount++
+	}
+
+	return &Histogram{
+		lowest:                      lowest,
+		highest:                     highest,
+		sigFigs:                     sigFigs,
+		unitMagnitude:               unitMagnitude,
+		subBucketHalfCountMagnitude: subBucketHalfCountMagnitude,
+		subBucketCount:              subBucketCount,
+		subBucketHalfCount:          subBucketCount / 2,
+		subBucketMask:               int64(subBucketCount-1) << unitMagnitude,
+		bucketCount:                 bucketCount,
+		leadingZeroCountBase:        64 - unitMagnitude - subBucketHalfCountMagnitude - 1,
+		counts:                      make([]int64, (bucketCount+1)*(subBucketCount/2)),
+		min:                         math.MaxInt64,
+	}, nil
+}
+
+// Record adds a single value
+func (h *Histogram) Record(v int64) error {
+	return h.RecordN(v, 1)
+}
+
+// RecordN adds n occurrences of a value
+func (h *Histogram) RecordN(v, n int64) error {
+	if v < 0 || v > h.highest {
+		return errors.New("value out of trackable range")
+	}
+	idx := h.countsIndexFor(v)
+	if idx < 0 || idx >= len(h.counts) {
+		return errors.New("value out of trackable range")
+	}
+	h.counts[idx] += n
+	h.totalCount += n
+	if v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	return nil
+}
+
+// Merge adds all values recorded in other. Both histograms must have been
+// created with the same parameters.
+func (h *Histogram) Merge(other *Histogram) error {
+	if other.lowest != h.lowest || other.highest != h.highest || other.sigFigs != h.sigFigs {
+		return errors.New("cannot merge histograms with different ranges or precision")
+	}
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	h.totalCount += other.totalCount
+	if other.totalCount > 0 {
+		if other.min < h.min {
+			h.min = other.min
+		}
+		if other.max > h.max {
+			h.max = other.max
+		}
+	}
+	return nil
+}
+
+// Copy returns an independent copy of the histogram
+func (h *Histogram) Copy() *Histogram {
+	c := *h
+	c.counts = append([]int64(nil), h.counts...)
+	return &c
+}
+
+// Reset clears all recorded values
+func (h *Histogram) Reset() {
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+	h.totalCount = 0
+	h.min = math.MaxInt64
+	h.max = 0
+}
+
+// TotalCount is the number of recorded values
+func (h *Histogram) TotalCount() int64 {
+	return h.totalCount
+}
+
+// Min is the smallest recorded value, or 0 when empty
+func (h *Histogram) Min() int64 {
+	if h.totalCount == 0 {
+		return 0
+	}
+	return h.min
+}
+
+// Max is the largest recorded value, or 0 when empty
+func (h *Histogram) Max() int64 {
+	return h.max
+}
+
+// Mean of the recorded values
+func (h *Histogram) Mean() float64 {
+	if h.totalCount == 0 {
+		return 0
+	}
+	var total float64
+	for i, c := range h.counts {
+		if c != 0 {
+			total += float64(c) * float64(h.medianEquivalentValue(h.valueFromIndex(i)))
+		}
+	}
+	return total / float64(h.totalCount)
+}
+
+// StdDev is the standard deviation of the recorded values
+func (h *Histogram) StdDev() float64 {
+	if h.totalCount == 0 {
+		return 0
+	}
+	mean := h.Mean()
+	var total float64
+	for i, c := range h.counts {
+		if c != 0 {
+			d := float64(h.medianEquivalentValue(h.valueFromIndex(i))) - mean
+			total += d * d * float64(c)
+		}
+	}
+	return math.Sqrt(total / float64(h.totalCount))
+}
+
+// ValueAtQuantile returns the value below which the fraction q (0-1) of
+// recorded values fall
+func (h *Histogram) ValueAtQuantile(q float64) int64 {
+	if h.totalCount == 0 {
+		return 0
+	}
+	if q > 1 {
+		q = 1
+	}
+	countAtQuantile := int64(q*float64(h.totalCount) + 0.5)
+	if countAtQuantile < 1 {
+		countAtQuantile = 1
+	}
+
+	var total int64
+	for i, c := range h.counts {
+		total += c
+		if total >= countAtQuantile {
+			v := h.highestEquivalentValue(h.valueFromIndex(i))
+			if v > h.max {
+				v = h.max
+			}
+			return v
+		}
+	}
+	return h.max
+}
+
+// Bar is one populated bucket of the histogram
+type Bar struct {
+	From, To int64 // inclusive range of equivalent values
+	Count    int64
+}
+
+// Distribution returns the populated buckets in ascending order
+func (h *Histogram) Distribution() []Bar {
+	var bars []Bar
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		v := h.valueFromIndex(i)
+		bars = append(bars, Bar{From: h.lowestEquivalentValue(v), To: h.highestEquivalentValue(v), Count: c})
+	}
+	return bars
+}
+
+func (h *Histogram) bucketIndex(v int64) int {
+	return h.leadingZeroCountBase - bits.LeadingZeros64(uint64(v|h.subBucketMask))
+}
+
+func (h *Histogram) subBucketIndex(v int64, bucketIdx int) int {
+	return int(v >> uint(bucketIdx+h.unitMagnitude))
+}
+
+func (h *Histogram) countsIndex(bucketIdx, subBucketIdx int) int {
+	bucketBaseIdx := (bucketIdx + 1) << uint(h.subBucketHalfCountMagnitude)
+	offsetInBucket := subBucketIdx - h.subBucketHalfCount
+	return bucketBaseIdx + offsetInBucket
+}
+
+func (h *Histogram) countsIndexFor(v int64) int {
+	bucketIdx := h.bucketIndex(v)
+	return h.countsIndex(bucketIdx, h.subBucketIndex(v, bucketIdx))
+}
+
+func (h *Histogram) valueFromIndex(idx int) int64 {
+	bucketIdx := (idx >> uint(h.subBucketHalfCountMagnitude)) - 1
+	subBucketIdx := (idx & (h.subBucketHalfCount - 1)) + h.subBucketHalfCount
+	if bucketIdx < 0 {
+		subBucketIdx -= h.subBucketHalfCount
+		bucketIdx = 0
+	}
+	return int64(subBucketIdx) << uint(bucketIdx+h.unitMagnitude)
+}
+
+func (h *Histogram) sizeOfEquivalentValueRange(v int64) int64 {
+	bucketIdx := h.bucketIndex(v)
+	subBucketIdx := h.subBucketIndex(v, bucketIdx)
+	adjustedBucket := bucketIdx
+	if subBucketIdx >= h.subBucketCount {
+		adjustedBucket++
+	}
+	return int64(1) << uint(h.unitMagnitude+adjustedBucket)
+}
+
+func (h *Histogram) lowestEquivalentValue(v int64) int64 {
+	bucketIdx := h.bucketIndex(v)
+	subBucketIdx := h.subBucketIndex(v, bucketIdx)
+	return int64(subBucketIdx) << uint(bucketIdx+h.unitMagnitude)
+}
+
+func (h *Histogram) highestEquivalentValue(v int64) int64 {
+	return h.lowestEquivalentValue(v) + h.sizeOfEquivalentValueRange(v) - 1
+}
+
+func (h *Histogram) medianEquivalentValue(v int64) int64 {
+	return h.lowestEquivalentValue(v) + h.sizeOfEquivalentValueRange(v)>>1
+}
+
+// NewLatency returns a histogram for latencies recorded in microseconds,
+// from 1µs up to an hour with 3 significant digits
+func NewLatency() *Histogram {
+	h, _ := New(1, int64(time.Hour/time.Microsecond), 3)
+	return h
+}
+
+// RecordDuration records d in microseconds, clamped to the trackable range
+func (h *Histogram) RecordDuration(d time.Duration) {
+	v := d.Microseconds()
+	if v < 0 {
+		v = 0
+	}
+	if v > h.highest {
+		v = h.highest
+	}
+	h.Record(v)
+}
+
+// Point of a percentile distribution
+type Point struct {
+	Quantile float64
+	Value    int64
+}
+
+// PercentileDistribution samples the value at increasingly fine quantiles
+// (0, 50%, 75%, 87.5%, ...), halving the distance to 100% every
+// ticksPerHalfDistance points, the way HdrHistogram reports it
+func (h *Histogram) PercentileDistribution(ticksPerHalfDistance int) []Point {
+	if h.totalCount == 0 || ticksPerHalfDistance < 1 {
+		return nil
+	}
+
+	// Past this quantile every value is the maximum
+	last := 1 - 1/float64(h.totalCount)
+
+	var points []Point
+	for i := 0; ; i++ {
+		q := 1 - math.Pow(0.5, float64(i)/float64(ticksPerHalfDistance))
+		if q >= last {
+			break
+		}
+		points = append(points, Point{Quantile: q, Value: h.ValueAtQuantile(q)})
+	}
+	return append(points, Point{Quantile: last, Value: h.Max()})
+}
@@ -0,0 +1,144 @@
+package histogram
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketCountCoversHighest(t *testing.T) {
+	// 2048 is exactly the first value the first bucket cannot hold
+	h, err := New(1, 2048, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Record(2048); err != nil {
+		t.Fatalf("recording the highest trackable value: %v", err)
+	}
+	if err := h.Record(2049); err == nil {
+		t.Fatal("recorded a value above the highest trackable value")
+	}
+}
+
+func TestRecordAtBucketBoundaries(t *testing.T) {
+	tests := []struct {
+		value    int64
+		from, to int64 // equivalent values
+	}{
+		{0, 0, 0},
+		{1, 1, 1},
+		{2047, 2047, 2047}, // last value with single unit resolution
+		{2048, 2048, 2049}, // first bucket of width 2
+		{2049, 2048, 2049},
+		{4095, 4094, 4095},
+		{4096, 4096, 4099}, // first bucket of width 4
+		{4099, 4096, 4099},
+	}
+	for _, tt := range tests {
+		h := NewLatency()
+		if err := h.Record(tt.value); err != nil {
+			t.Fatalf("Record(%d): %v", tt.value, err)
+		}
+		bars := h.Distribution()
+		if len(bars) != 1 || bars[0].From != tt.from || bars[0].To != tt.to || bars[0].Count != 1 {
+			t.Errorf("Record(%d) went to %+v, want [%d, %d]", tt.value, bars, tt.from, tt.to)
+		}
+		// A single value is reported exactly, clamped to the maximum
+		if v := h.ValueAtQuantile(0.5); v != tt.value {
+			t.Errorf("Record(%d): ValueAtQuantile(0.5) = %d", tt.value, v)
+		}
+	}
+}
+
+func TestValueAtQuantile(t *testing.T) {
+	h := NewLatency()
+	for v := int64(1); v <= 1000; v++ {
+		h.Record(v)
+	}
+	tests := []struct {
+		q    float64
+		want int64
+	}{
+		{0, 1},
+		{0.001, 1},
+		{0.5, 500},
+		{0.95, 950},
+		{0.99, 990},
+		{1, 1000},
+	}
+	for _, tt := range tests {
+		if got := h.ValueAtQuantile(tt.q); got != tt.want {
+			t.Errorf("ValueAtQuantile(%v) = %d, want %d", tt.q, got, tt.want)
+		}
+	}
+	if h.Min() != 1 || h.Max() != 1000 || h.TotalCount() != 1000 {
+		t.Errorf("min %d, max %d, count %d", h.Min(), h.Max(), h.TotalCount())
+	}
+	if mean := h.Mean(); mean != 500.5 {
+		t.Errorf("Mean() = %v, want 500.5", mean)
+	}
+
+	// Above 2048 values share buckets, the upper end of a bucket is reported
+	h = NewLatency()
+	h.Record(3000)
+	h.Record(3001)
+	h.Record(5000)
+	if got := h.ValueAtQuantile(0.5); got != 3001 {
+		t.Errorf("ValueAtQuantile(0.5) = %d, want 3001", got)
+	}
+}
+
+func TestRecordDurationClamps(t *testing.T) {
+	h := NewLatency()
+	h.RecordDuration(-time.Second)
+	h.RecordDuration(2 * time.Hour)
+	if h.Min() != 0 || h.Max() != int64(time.Hour/time.Microsecond) {
+		t.Errorf("min %d, max %d", h.Min(), h.Max())
+	}
+}
+
+func TestMerge(t *testing.T) {
+	all, a, b := NewLatency(), NewLatency(), NewLatency()
+	for v := int64(1); v <= 5000; v++ {
+		all.Record(v * 7)
+		if v%2 == 0 {
+			a.Record(v * 7)
+		} else {
+			b.Record(v * 7)
+		}
+	}
+	merged := NewLatency()
+	if err := merged.Merge(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := merged.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	// Merging an empty histogram changes nothing
+	if err := merged.Merge(NewLatency()); err != nil {
+		t.Fatal(err)
+	}
+
+	if merged.TotalCount() != all.TotalCount() || merged.Min() != all.Min() || merged.Max() != all.Max() {
+		t.Errorf("merged count %d min %d max %d, want %d %d %d",
+			merged.TotalCount(), merged.Min(), merged.Max(), all.TotalCount(), all.Min(), all.Max())
+	}
+	for _, q := range []float64{0, 0.5, 0.9, 0.99, 0.999, 1} {
+		if got, want := merged.ValueAtQuantile(q), all.ValueAtQuantile(q); got != want {
+			t.Errorf("ValueAtQuantile(%v) = %d after merging, want %d", q, got, want)
+		}
+	}
+
+	other, _ := New(1, 1000, 3)
+	if err := merged.Merge(other); err == nil {
+		t.Error("merged histograms with different ranges")
+	}
+}
+
+func TestReset(t *testing.T) {
+	h := NewLatency()
+	h.Record(42)
+	h.Reset()
+	if h.TotalCount() != 0 || h.Min() != 0 || h.Max() != 0 || len(h.Distribution()) != 0 {
+		t.Errorf("not empty after Reset: count %d min %d max %d", h.TotalCount(), h.Min(), h.Max())
+	}
+}
@@ -0,0 +1,129 @@
+package histogram
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Cookies of the V2 HdrHistogram encoding with 8 byte words
+const (
+	encodingCookie           = 0x1c849303 | 0x10
+	compressedEncodingCookie = 0x1c849304 | 0x10
+)
+
+// maxValueUnitRatio converts the recorded microseconds into the milliseconds
+// shown in the Interval_Max column
+const maxValueUnitRatio = 1000.0
+
+// Encode serialises the histogram in the V2 HdrHistogram encoding
+func (h *Histogram) Encode() []byte {
+	var payload bytes.Buffer
+	countsLimit := 0
+	if h.totalCount > 0 {
+		countsLimit = h.countsIndexFor(h.max) + 1
+	}
+	for i := 0; i < countsLimit; {
+		count := h.counts[i]
+		i++
+		if count == 0 {
+			// Runs of empty buckets are written as a single negative count
+			zeros := int64(1)
+			for i < countsLimit && h.counts[i] == 0 {
+				zeros++
+				i++
+			}
+			if zeros > 1 {
+				putZigZag(&payload, -zeros)
+				continue
+			}
+		}
+		putZigZag(&payload, count)
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, int32(encodingCookie))
+	binary.Write(&buf, binary.BigEndian, int32(payload.Len()))
+	binary.Write(&buf, binary.BigEndian, int32(0)) // normalizing index offset
+	binary.Write(&buf, binary.BigEndian, int32(h.sigFigs))
+	binary.Write(&buf, binary.BigEndian, h.lowest)
+	binary.Write(&buf, binary.BigEndian, h.highest)
+	binary.Write(&buf, binary.BigEndian, math.Float64bits(1.0)) // integer to double conversion ratio
+	buf.Write(payload.Bytes())
+	return buf.Bytes()
+}
+
+// EncodeCompressed serialises the histogram in the compressed V2 encoding used
+// by histogram log files
+func (h *Histogram) EncodeCompressed() ([]byte, error) {
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	if _, err := w.Write(h.Encode()); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, int32(compressedEncodingCookie))
+	binary.Write(&buf, binary.BigEndian, int32(compressed.Len()))
+	buf.Write(compressed.Bytes())
+	return buf.Bytes(), nil
+}
+
+// putZigZag writes v as a ZigZag LEB128 varint of at most 9 bytes
+func putZigZag(buf *bytes.Buffer, v int64) {
+	u := uint64((v << 1) ^ (v >> 63))
+	for i := 0; i < 8; i++ {
+		if u < 0x80 {
+			buf.WriteByte(byte(u))
+			return
+		}
+		buf.WriteByte(byte(u&0x7f) | 0x80)
+		u >>= 7
+	}
+	buf.WriteByte(byte(u))
+}
+
+// LogWriter writes interval histograms in the HdrHistogram log format (v1.3)
+type LogWriter struct {
+	w         io.Writer
+	startTime time.Time
+}
+
+// NewLogWriter writes the log header. Timestamps of intervals are relative to start.
+func NewLogWriter(w io.Writer, start time.Time) (*LogWriter, error) {
+	seconds := float64(start.UnixNano()) / 1e9
+	_, err := fmt.Fprintf(w, "#[Histogram log format version 1.3]\n"+
+		"#[StartTime: %.3f (seconds since epoch), %s]\n"+
+		"#[BaseTime: %.3f (seconds since epoch)]\n"+
+		"#[Values recorded in microseconds, Interval_Max in milliseconds]\n"+
+		"\"StartTimestamp\",\"Interval_Length\",\"Interval_Max\",\"Interval_Compressed_Histogram\"\n",
+		seconds, start.Format(time.UnixDate), seconds)
+	if err != nil {
+		return nil, err
+	}
+	return &LogWriter{w: w, startTime: start}, nil
+}
+
+// Write appends an interval histogram. The tag must not contain commas or whitespace.
+func (l *LogWriter) Write(tag string, start time.Time, length time.Duration, h *Histogram) error {
+	encoded, err := h.EncodeCompressed()
+	if err != nil {
+		return err
+	}
+	prefix := ""
+	if tag != "" {
+		prefix = "Tag=" + tag + ","
+	}
+	_, err = fmt.Fprintf(l.w, "%s%.3f,%.3f,%.3f,%s\n", prefix,
+		start.Sub(l.startTime).Seconds(), length.Seconds(), float64(h.Max())/maxValueUnitRatio,
+		base64.StdEncoding.EncodeToString(encoded))
+	return err
+}
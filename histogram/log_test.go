@@ -0,0 +1,141 @@
+package histogram
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestZigZag(t *testing.T) {
+	tests := []struct {
+		v    int64
+		want []byte
+	}{
+		{0, []byte{0x00}},
+		{-1, []byte{0x01}},
+		{1, []byte{0x02}},
+		{63, []byte{0x7e}},
+		{-64, []byte{0x7f}},
+		{64, []byte{0x80, 0x01}},
+		{-998, []byte{0xcb, 0x0f}},
+		{8191, []byte{0xfe, 0x7f}},
+		{8192, []byte{0x80, 0x80, 0x01}},
+		// The ninth byte carries all eight remaining bits
+		{1<<63 - 1, []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{-1 << 63, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		putZigZag(&buf, tt.v)
+		if !bytes.Equal(buf.Bytes(), tt.want) {
+			t.Errorf("putZigZag(%d) = % x, want % x", tt.v, buf.Bytes(), tt.want)
+		}
+	}
+}
+
+// payload is the V2 encoding of a latency histogram with 1 recorded once and
+// 1000 twice, laid out as in HdrHistogram's encodeIntoByteBuffer: a 40 byte
+// header followed by the ZigZag counts up to the maximum's, a single empty
+// bucket written as 0 and a run of them as minus its length.
+var payload = []byte{
+	0x1c, 0x84, 0x93, 0x13, // V2 cookie
+	0x00, 0x00, 0x00, 0x05, // payload length
+	0x00, 0x00, 0x00, 0x00, // normalizing index offset
+	0x00, 0x00, 0x00, 0x03, // significant digits
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, // lowest trackable value
+	0x00, 0x00, 0x00, 0x00, 0xd6, 0x93, 0xa4, 0x00, // highest trackable value, an hour in µs
+	0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // integer to double ratio, 1.0
+	0x00,       // index 0, empty
+	0x02,       // index 1, 1 value
+	0xcb, 0x0f, // indexes 2-999, 998 empty
+	0x04, // index 1000, 2 values
+}
+
+func TestEncode(t *testing.T) {
+	h := NewLatency()
+	h.Record(1)
+	h.RecordN(1000, 2)
+	if got := h.Encode(); !bytes.Equal(got, payload) {
+		t.Errorf("Encode() =\n% x\nwant\n% x", got, payload)
+	}
+
+	// An empty histogram is just the header
+	empty := NewLatency().Encode()
+	if len(empty) != 40 || binary.BigEndian.Uint32(empty[4:8]) != 0 {
+		t.Errorf("empty histogram encoded as % x", empty)
+	}
+}
+
+func TestEncodeCompressed(t *testing.T) {
+	h := NewLatency()
+	h.Record(1)
+	h.RecordN(1000, 2)
+	encoded, err := h.EncodeCompressed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cookie := binary.BigEndian.Uint32(encoded[0:4]); cookie != 0x1c849314 {
+		t.Errorf("cookie %#x, want the compressed V2 cookie 0x1c849314", cookie)
+	}
+	if length := binary.BigEndian.Uint32(encoded[4:8]); int(length) != len(encoded)-8 {
+		t.Errorf("length %d, want %d", length, len(encoded)-8)
+	}
+	if got := decompress(t, encoded[8:]); !bytes.Equal(got, payload) {
+		t.Errorf("decompressed to\n% x\nwant\n% x", got, payload)
+	}
+}
+
+func TestLogWriter(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	var buf bytes.Buffer
+	log, err := NewLogWriter(&buf, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewLatency()
+	h.Record(1)
+	h.RecordN(1000, 2)
+	if err := log.Write("MySQL/table1/read/w1", start.Add(1500*time.Millisecond), 2*time.Second, h); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("got %d lines:\n%s", len(lines), buf.String())
+	}
+	if lines[0] != "#[Histogram log format version 1.3]" || !strings.HasPrefix(lines[1], "#[StartTime: 1700000000.000 ") {
+		t.Errorf("header:\n%s\n%s", lines[0], lines[1])
+	}
+	fields := strings.Split(lines[5], ",")
+	if len(fields) != 5 {
+		t.Fatalf("interval line %q", lines[5])
+	}
+	if want := []string{"Tag=MySQL/table1/read/w1", "1.500", "2.000", "1.000"}; strings.Join(fields[:4], ",") != strings.Join(want, ",") {
+		t.Errorf("interval line %q, want it to start with %q", lines[5], strings.Join(want, ","))
+	}
+	encoded, err := base64.StdEncoding.DecodeString(fields[4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decompress(t, encoded[8:]); !bytes.Equal(got, payload) {
+		t.Errorf("logged histogram decodes to\n% x\nwant\n% x", got, payload)
+	}
+}
+
+func decompress(t *testing.T, data []byte) []byte {
+	t.Helper()
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
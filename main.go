@@ -1,54 +1,91 @@
 package main
 
 import (
+	"benchmarkDB/bench"
+	"benchmarkDB/explain"
+	"benchmarkDB/fixture"
+	"benchmarkDB/pool"
+	"benchmarkDB/report/plot"
+	"benchmarkDB/runctx"
+	"benchmarkDB/server"
 	ui "benchmarkDB/ui"
 	"context"
+	"flag"
 	"fmt"
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
 
-	"database/sql"
-
 	_ "github.com/go-sql-driver/mysql"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func main() {
-	fmt.Println("************************************************")
+	flag.StringVar(&plot.Default.Dir, "plots-dir", plot.Default.Dir, "directory charts are written to")
+	flag.StringVar(&plot.Default.Prefix, "plots-prefix", plot.Default.Prefix, "prefix of chart file names")
+	flag.StringVar(&plot.Default.Format, "plots-format", plot.Default.Format, "chart file format: png, svg or pdf")
+	flag.Float64Var(&plot.Default.Width, "plots-width", plot.Default.Width, "chart width in inches")
+	flag.Float64Var(&plot.Default.Height, "plots-height", plot.Default.Height, "chart height in inches")
+	flag.IntVar(&plot.Default.DPI, "plots-dpi", plot.Default.DPI, "resolution of png charts")
+	flag.DurationVar(&runctx.Default.Deadline, "deadline", runctx.Default.Deadline, "stop the whole run after this long, 0 for no limit")
+	flag.DurationVar(&runctx.Default.OpTimeout, "op-timeout", runctx.Default.OpTimeout, "time out a single database operation after this long, 0 for no limit")
+	flag.BoolVar(&fixture.Enabled, "reset", fixture.Enabled, "reset the tables to dataset/*.csv before and after each benchmark")
+	flag.BoolVar(&server.Metrics, "server-metrics", server.Metrics, "poll the servers' status counters while each operation runs")
+	flag.DurationVar(&server.Interval, "server-metrics-interval", server.Interval, "time between two polls of the servers' status")
+	flag.BoolVar(&explain.Enabled, "explain", explain.Enabled, "explain the query of each operation and record its plan")
+	flag.IntVar(&pool.Default.MySQLMaxOpenConns, "mysql-max-open-conns", pool.Default.MySQLMaxOpenConns, "most MySQL connections open at once, 0 for no limit")
+	flag.IntVar(&pool.Default.MySQLMaxIdleConns, "mysql-max-idle-conns", pool.Default.MySQLMaxIdleConns, "most idle MySQL connections kept open")
+	flag.DurationVar(&pool.Default.MySQLConnMaxLifetime, "mysql-conn-max-lifetime", pool.Default.MySQLConnMaxLifetime, "longest a MySQL connection is reused, 0 for no limit")
+	flag.Uint64Var(&pool.Default.MongoMaxPoolSize, "mongo-max-pool-size", pool.Default.MongoMaxPoolSize, "most MongoDB connections per server, 0 for no limit")
+	flag.Uint64Var(&pool.Default.MongoMinPoolSize, "mongo-min-pool-size", pool.Default.MongoMinPoolSize, "MongoDB connections kept open per server")
+	flag.DurationVar(&pool.Default.MongoMaxConnIdleTime, "mongo-max-conn-idle-time", pool.Default.MongoMaxConnIdleTime, "longest a MongoDB connection stays idle, 0 for no limit")
+	flag.Usage = func() { fmt.Fprint(flag.CommandLine.Output(), usage) }
+	flag.Parse()
 
-	// Connection to MySql
+	if err := plot.Default.Validate(); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(2)
+	}
+	if err := runctx.Default.Validate(); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(2)
+	}
+	if server.Interval <= 0 {
+		fmt.Println("Error: server metrics interval must be positive")
+		os.Exit(2)
+	}
+	if err := pool.Default.Validate(); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(2)
+	}
 
-	sqldb, err := sql.Open("mysql", "MYMYSQL_USERNAME:SQL_PASS@tcp(localhost:3306)/MYSQL_DATABASE")
-	if err != nil {
-		fmt.Println("Error connecting to database:", err)
+	// Run a single benchmark straight from the command line, skipping the menu
+	if flag.NArg() > 0 {
+		if err := runCommand(flag.Args()); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
 		return
 	}
 
-	err = sqldb.Ping()
+	fmt.Println("************************************************")
+
+	// Connection to MySql
+	ctx := context.Background()
+	sqldb, err := bench.MySQL(ctx, pool.Default)
 	if err != nil {
-		fmt.Println("Error pinging database:", err)
+		fmt.Println("Error connecting to database:", err)
 		return
 	}
+	defer sqldb.Close()
 
 	fmt.Println("Connected to MySQL database!")
 
 	// connection to MongoDB
-	const uri = "mongodb://localhost:27017/MONGODB_DATABASE"
-	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
-	opts := options.Client().ApplyURI(uri).SetServerAPIOptions(serverAPI)
-
-	client, err := mongo.Connect(context.TODO(), opts)
+	client, err := bench.Mongo(ctx, pool.Default)
 	if err != nil {
 		panic(err)
 	}
-
-	var result bson.M
-	if err := client.Database("admin").RunCommand(context.TODO(), bson.D{{"ping", 1}}).Decode(&result); err != nil {
-		panic(err)
-	}
+	defer bench.DisconnectMongo(client)
 	fmt.Println("Pinged the server. Successfully connected to MongoDB!")
 
 	fmt.Println("************************************************")
@@ -58,6 +95,4 @@ func main() {
 		fmt.Printf("Error occured: %v", err)
 		os.Exit(1)
 	}
-
-	defer sqldb.Close()
 }
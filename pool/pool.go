@@ -0,0 +1,208 @@
+// Package pool configures the connection pools of the MySQL and MongoDB
+// clients and measures how long operations wait to get a connection.
+package pool
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"benchmarkDB/results"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Config of the pools, set from the command line
+type Config struct {
+	MySQLMaxOpenConns    int           // most connections open at once, 0 for no limit
+	MySQLMaxIdleConns    int           // most idle connections kept open, 0 to keep none
+	MySQLConnMaxLifetime time.Duration // longest a connection is reused, 0 for no limit
+	MongoMaxPoolSize     uint64        // most connections per server, 0 for no limit
+	MongoMinPoolSize     uint64        // connections kept open per server even when idle
+	MongoMaxConnIdleTime time.Duration // longest a connection stays idle before it is closed, 0 for no limit
+}
+
+// Default pool settings used by every benchmark, those of the drivers
+var Default = Config{MySQLMaxIdleConns: 2, MongoMaxPoolSize: 100}
+
+// Validate checks that the sizes and times are not negative and that the
+// MongoDB pool can hold its minimum
+func (c Config) Validate() error {
+	if c.MySQLMaxOpenConns < 0 || c.MySQLMaxIdleConns < 0 || c.MySQLConnMaxLifetime < 0 || c.MongoMaxConnIdleTime < 0 {
+		return errors.New("pool sizes and connection times must not be negative")
+	}
+	if c.MongoMaxPoolSize > 0 && c.MongoMinPoolSize > c.MongoMaxPoolSize {
+		return errors.New("mongo min pool size must not exceed the max pool size")
+	}
+	return nil
+}
+
+// Sized is the configuration with both pools limited to size connections,
+// all of which may stay idle, so the pool size alone limits concurrency
+func (c Config) Sized(size int) Config {
+	c.MySQLMaxOpenConns = size
+	c.MySQLMaxIdleConns = size
+	c.MongoMaxPoolSize = uint64(size)
+	if c.MongoMinPoolSize > c.MongoMaxPoolSize {
+		c.MongoMinPoolSize = c.MongoMaxPoolSize
+	}
+	return c
+}
+
+// MySQL applies the settings to the pool of db
+func (c Config) MySQL(db *sql.DB) {
+	db.SetMaxOpenConns(c.MySQLMaxOpenConns)
+	db.SetMaxIdleConns(c.MySQLMaxIdleConns)
+	db.SetConnMaxLifetime(c.MySQLConnMaxLifetime)
+}
+
+// Mongo applies the settings to the options of a client and installs a
+// monitor of its own, which Track ties to the client connected with them
+func (c Config) Mongo(clientOptions *options.ClientOptions) *options.ClientOptions {
+	m := &checkouts{}
+	monitors.Lock()
+	monitors.byOptions[clientOptions] = m
+	monitors.Unlock()
+	return clientOptions.
+		SetMaxPoolSize(c.MongoMaxPoolSize).
+		SetMinPoolSize(c.MongoMinPoolSize).
+		SetMaxConnIdleTime(c.MongoMaxConnIdleTime).
+		SetPoolMonitor(&event.PoolMonitor{Event: m.event})
+}
+
+// monitors are the pool monitors installed by Config.Mongo, by the options
+// they were installed in until Track ties them to their client
+var monitors = struct {
+	sync.Mutex
+	byOptions map[*options.ClientOptions]*checkouts
+	byClient  map[*mongo.Client]*checkouts
+}{
+	byOptions: make(map[*options.ClientOptions]*checkouts),
+	byClient:  make(map[*mongo.Client]*checkouts),
+}
+
+// Track ties the monitor Config.Mongo installed in clientOptions to the
+// client connected with them, Watch reads its checkouts from there on. A nil
+// client, one that failed to connect, drops the monitor.
+func Track(client *mongo.Client, clientOptions *options.ClientOptions) {
+	monitors.Lock()
+	defer monitors.Unlock()
+	m, ok := monitors.byOptions[clientOptions]
+	if !ok {
+		return
+	}
+	delete(monitors.byOptions, clientOptions)
+	if client != nil {
+		monitors.byClient[client] = m
+	}
+}
+
+// Forget drops the monitor of a disconnected client
+func Forget(client *mongo.Client) {
+	monitors.Lock()
+	defer monitors.Unlock()
+	delete(monitors.byClient, client)
+}
+
+// monitorOf is the monitor of a tracked client, nil for any other
+func monitorOf(client *mongo.Client) *checkouts {
+	monitors.Lock()
+	defer monitors.Unlock()
+	return monitors.byClient[client]
+}
+
+// checkouts tallies the pool events of one MongoDB client
+type checkouts struct {
+	mu      sync.Mutex
+	count   int64
+	wait    time.Duration
+	maxWait time.Duration
+	open    int
+	size    int
+}
+
+func (c *checkouts) event(e *event.PoolEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch e.Type {
+	case event.PoolCreated:
+		if e.PoolOptions != nil {
+			c.size = int(e.PoolOptions.MaxPoolSize)
+		}
+	case event.ConnectionCreated:
+		c.open++
+	case event.ConnectionClosed:
+		c.open--
+	case event.GetSucceeded:
+		// The driver times each checkout, including opening a connection
+		// when none is idle
+		c.count++
+		c.wait += e.Duration
+		if e.Duration > c.maxWait {
+			c.maxWait = e.Duration
+		}
+	}
+}
+
+// Watcher counts the connection checkouts of one client while an operation
+// runs
+type Watcher struct {
+	client     interface{}
+	monitor    *checkouts
+	mysql      sql.DBStats
+	mongoCount int64
+	mongoWait  time.Duration
+}
+
+// Watch starts counting the checkouts of client. The longest MongoDB
+// checkout is counted from here on.
+func Watch(client interface{}) *Watcher {
+	w := &Watcher{client: client}
+	switch c := client.(type) {
+	case *mongo.Client:
+		w.monitor = monitorOf(c)
+		if w.monitor == nil {
+			break
+		}
+		w.monitor.mu.Lock()
+		w.monitor.maxWait = 0
+		w.mongoCount, w.mongoWait = w.monitor.count, w.monitor.wait
+		w.monitor.mu.Unlock()
+	case *sql.DB:
+		w.mysql = c.Stats()
+	}
+	return w
+}
+
+// Stop returns the checkouts since Watch, nil for an unsupported client or a
+// MongoDB client that is not tracked
+func (w *Watcher) Stop() *results.Pool {
+	switch c := w.client.(type) {
+	case *mongo.Client:
+		if w.monitor == nil {
+			return nil
+		}
+		w.monitor.mu.Lock()
+		defer w.monitor.mu.Unlock()
+		return &results.Pool{
+			Size:           w.monitor.size,
+			Checkouts:      w.monitor.count - w.mongoCount,
+			WaitSeconds:    (w.monitor.wait - w.mongoWait).Seconds(),
+			MaxWaitSeconds: w.monitor.maxWait.Seconds(),
+			Open:           w.monitor.open,
+		}
+	case *sql.DB:
+		stats := c.Stats()
+		return &results.Pool{
+			Size:        stats.MaxOpenConnections,
+			Waits:       stats.WaitCount - w.mysql.WaitCount,
+			WaitSeconds: (stats.WaitDuration - w.mysql.WaitDuration).Seconds(),
+			Open:        stats.OpenConnections,
+		}
+	default:
+		return nil
+	}
+}
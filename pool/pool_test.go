@@ -0,0 +1,63 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// connect returns a client, which does not dial until it is used, and the
+// monitor Config.Mongo installed in its options
+func connect(t *testing.T, c Config) (*mongo.Client, func(*event.PoolEvent)) {
+	t.Helper()
+	clientOptions := c.Mongo(options.Client().ApplyURI("mongodb://localhost:1"))
+	client, err := mongo.Connect(context.Background(), clientOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Track(client, clientOptions)
+	t.Cleanup(func() {
+		client.Disconnect(context.Background())
+		Forget(client)
+	})
+	return client, clientOptions.PoolMonitor.Event
+}
+
+func TestWatchCountsEachClientApart(t *testing.T) {
+	a, eventA := connect(t, Default.Sized(4))
+	b, eventB := connect(t, Default.Sized(8))
+
+	eventA(&event.PoolEvent{Type: event.ConnectionCreated})
+	eventA(&event.PoolEvent{Type: event.ConnectionCreated})
+	watchA, watchB := Watch(a), Watch(b)
+	eventA(&event.PoolEvent{Type: event.GetSucceeded, Duration: 3 * time.Millisecond})
+	eventA(&event.PoolEvent{Type: event.GetSucceeded, Duration: time.Millisecond})
+	// A pool created by another client does not reset the open connections
+	eventB(&event.PoolEvent{Type: event.PoolCreated, PoolOptions: &event.MonitorPoolOptions{MaxPoolSize: 8}})
+	eventB(&event.PoolEvent{Type: event.ConnectionCreated})
+	eventB(&event.PoolEvent{Type: event.GetSucceeded, Duration: 5 * time.Millisecond})
+
+	got := watchA.Stop()
+	if got == nil || got.Checkouts != 2 || got.Open != 2 || got.MaxWaitSeconds != 0.003 || got.WaitSeconds != 0.004 {
+		t.Errorf("first client: %+v", got)
+	}
+	got = watchB.Stop()
+	if got == nil || got.Checkouts != 1 || got.Open != 1 || got.Size != 8 || got.MaxWaitSeconds != 0.005 {
+		t.Errorf("second client: %+v", got)
+	}
+}
+
+func TestWatchUntrackedClient(t *testing.T) {
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+	if got := Watch(client).Stop(); got != nil {
+		t.Errorf("untracked client: %+v", got)
+	}
+}
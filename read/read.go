@@ -5,127 +5,186 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"os"
+	"sync"
 	"time"
 
+	"benchmarkDB/bench"
+	"benchmarkDB/dataset"
+	"benchmarkDB/explain"
+	"benchmarkDB/report/plot"
+	"benchmarkDB/results"
+	"benchmarkDB/runctx"
+	"benchmarkDB/server"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
-	"gonum.org/v1/plot/vg"
 )
 
 func Read() {
 	tables := []string{"table1", "table2", "table3", "table4"} // Representing MongoDB collections or MySQL tables
-	year := "2018"
+	year := 2018
 	field := "Year"
-	var mongoClient *mongo.Client
-	var mysqlDB *sql.DB
-	var err error // Declare err outside of the mongoClient initialization
 
-	// Initialize MongoDB client
-	mongoClient, err = initMongoClient() // Assign to err without :=
-	if err != nil {
-		fmt.Println("Error initializing MongoDB client:", err)
-		return
-	}
-	defer mongoClient.Disconnect(context.Background())
+	// Connected to both backends, starting from the seed data
+	ctx, b := bench.Start(tables)
+	mongoClient, mysqlDB := b.Mongo, b.MySQL
 
-	// Initialize MySQL client
-	mysqlDB, err = initMySQLClient() // Add this line to initialize mysqlDB
-	if err != nil {
-		fmt.Println("Error initializing MySQL client:", err)
-		return
-	}
-	defer mysqlDB.Close()
+	run := results.NewRun("read", results.Config{})
+	run.Environment.Servers = b.Servers()
 
-	// Single Threaded
+	// Single Threaded, repeated to get a latency distribution
 	fmt.Println("************Performing single-threaded reads***************")
-	singleThreadedMongoTimes := make([]float64, len(tables))
-	singleThreadedMySQLTimes := make([]float64, len(tables))
+	for _, table := range tables {
+		if ctx.Err() != nil {
+			break
+		}
+		rows, _ := dataset.Rows(table)
 
-	for i, table := range tables {
-		start := time.Now()
-		err := singleThreadedRead(mongoClient, table, field, year)
-		if err != nil {
-			fmt.Printf("Error reading %s in MongoDB: %v\n", table, err)
-		} else {
-			singleThreadedMongoTimes[i] = time.Since(start).Seconds()
-			fmt.Println("Time taken for single-threaded MongoDB read in", table+":", time.Since(start))
+		// Untimed, how each backend finds the rows of the year
+		mongoPlan := explain.Run(ctx, mongoClient, readQuery(table, field, year))
+		mysqlPlan := explain.Run(ctx, mysqlDB, readQuery(table, field, year))
+
+		watch := server.Watch(ctx, mongoClient, server.Report(run, "MongoDB", table))
+		latencies, errs := timedReads(ctx, mongoClient, table, field, year)
+		bench.PrintErrors("reading "+table+" in MongoDB", errs)
+		r := results.Iterations("MongoDB", table, "single-threaded read", latencies)
+		r.Rows = rows
+		r.Server = watch.Stop()
+		r.Plan = mongoPlan
+		run.Add(r, errs...)
+		if len(latencies) > 0 {
+			t := mean(latencies)
+			fmt.Println("Time taken for single-threaded MongoDB read in", dataset.Label(table)+":", seconds(t), perRow(table, t))
 		}
 
-		start = time.Now()
-		err = singleThreadedRead(mysqlDB, table, field, year)
-		if err != nil {
-			fmt.Printf("Error reading %s in MySQL: %v\n", table, err)
-		} else {
-			singleThreadedMySQLTimes[i] = time.Since(start).Seconds()
-			fmt.Println("    Time taken for single-threaded MySQL read in", table+":", time.Since(start))
+		watch = server.Watch(ctx, mysqlDB, server.Report(run, "MySQL", table))
+		latencies, errs = timedReads(ctx, mysqlDB, table, field, year)
+		bench.PrintErrors("reading "+table+" in MySQL", errs)
+		r = results.Iterations("MySQL", table, "single-threaded read", latencies)
+		r.Rows = rows
+		r.Server = watch.Stop()
+		r.Plan = mysqlPlan
+		run.Add(r, errs...)
+		if len(latencies) > 0 {
+			t := mean(latencies)
+			fmt.Println("    Time taken for single-threaded MySQL read in", dataset.Label(table)+":", seconds(t), perRow(table, t))
 		}
 	}
 	fmt.Println("***********************************************************")
 
-	// Multi Threaded
+	// Multi Threaded, the tables of a backend are read concurrently and each
+	// read is timed until it finished
 	fmt.Println("************Performing multi-threaded reads***************")
-	multiThreadedMongoTimes := make([]float64, len(tables))
-	multiThreadedMySQLTimes := make([]float64, len(tables))
-
-	for i, table := range tables {
-		start := time.Now()
-		err := multiThreadedRead(mongoClient, table, field, year)
-		if err != nil {
-			fmt.Printf("Error reading %s in multi-threaded MongoDB: %v\n", table, err)
-		} else {
-			multiThreadedMongoTimes[i] = time.Since(start).Seconds()
-			fmt.Println("    Time taken for multi-threaded MongoDB read in", table+":", time.Since(start))
+	for _, backend := range []struct {
+		name   string
+		client interface{}
+	}{{"MongoDB", mongoClient}, {"MySQL", mysqlDB}} {
+		if ctx.Err() != nil {
+			break
 		}
+		var (
+			mu      sync.Mutex
+			pending sync.WaitGroup
+			times   = make(map[string]time.Duration)
+			errs    = make(map[string]error)
+		)
+		for _, table := range tables {
+			table := table
+			done := func(t time.Duration, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs[table] = err
+				} else {
+					times[table] = t
+				}
+			}
+			if err := multiThreadedRead(ctx, backend.client, table, field, year, &pending, done); err != nil {
+				done(0, err)
+			}
+		}
+		pending.Wait()
 
-		start = time.Now()
-		err = multiThreadedRead(mysqlDB, table, field, year)
-		if err != nil {
-			fmt.Printf("Error reading %s in multi-threaded MySQL: %v\n", table, err)
-		} else {
-			multiThreadedMySQLTimes[i] = time.Since(start).Seconds()
-			fmt.Println("    Time taken for multi-threaded MySQL read in", table+":", time.Since(start))
+		name := backend.name
+		for _, table := range tables {
+			if err, failed := errs[table]; failed {
+				fmt.Printf("Error reading %s in multi-threaded %s: %v\n", table, name, err)
+				run.AddError(name, table, "multi-threaded read", err)
+				continue
+			}
+			t, read := times[table]
+			if !read {
+				continue // the run stopped before the read finished
+			}
+			fmt.Println("    Time taken for multi-threaded", name, "read in", dataset.Label(table)+":", t, perRow(table, t.Seconds()))
+			r := results.Timing(name, table, "multi-threaded read", t, 1)
+			r.Rows, _ = dataset.Rows(table)
+			run.Add(r)
 		}
 	}
 	fmt.Println("**********************************************************")
 
 	// Plotting
-	err = plotTimeBarChart("Time taken for Single-Threaded Reads", tables, singleThreadedMongoTimes, singleThreadedMySQLTimes)
+	run.Finished = time.Now()
+	err := plotTimeBarChart("read", "Time taken for Single-Threaded Reads", filter(run.Results, "single-threaded read"), plot.Mean)
 	if err != nil {
 		fmt.Println("Error plotting single-threaded reads:", err)
 	}
-	err = plotTimeBarChart("Time taken for Multi-Threaded Reads", tables, multiThreadedMongoTimes, multiThreadedMySQLTimes)
+	err = plotTimeBarChart("read_multi_threaded", "Time taken for Multi-Threaded Reads", filter(run.Results, "multi-threaded read"), plot.Duration)
 	if err != nil {
 		fmt.Println("Error plotting multi-threaded reads:", err)
 	}
-
-	if err != nil { // Check if there's any error occurred during the reads
-		fmt.Println("Program completed with errors")
-		os.Exit(1) // Exit with non-zero exit code to indicate failure
+	err = plotScaling("Single-threaded read time vs. dataset size", filter(run.Results, "single-threaded read"))
+	if err != nil {
+		fmt.Println("Error plotting read scaling:", err)
 	}
+	err = plot.Distributions("read", "Single-threaded read", filter(run.Results, "single-threaded read"), label)
+	if err != nil {
+		fmt.Println("Error plotting read latency distributions:", err)
+	}
+	b.Finish(run)
+}
 
-	fmt.Println("Program completed successfully")
-	os.Exit(0)
+// Number of times each single-threaded read is repeated
+const iterations = 20
+
+// timedReads runs the read iterations times and returns the latency of each
+// successful read in seconds and the errors of the failed ones. It returns
+// early when the run is stopped.
+func timedReads(ctx context.Context, client interface{}, table, field string, year int) ([]float64, []error) {
+	latencies := make([]float64, 0, iterations)
+	var errs []error
+	for i := 0; i < iterations; i++ {
+		start := time.Now()
+		err := runctx.Do(ctx, func(ctx context.Context) error { return singleThreadedRead(ctx, client, table, field, year) })
+		if runctx.Stopped(err) {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		latencies = append(latencies, time.Since(start).Seconds())
+	}
+	return latencies, errs
 }
 
-func singleThreadedRead(client interface{}, table, field, year string) error {
+func singleThreadedRead(ctx context.Context, client interface{}, table, field string, year int) error {
 	switch c := client.(type) {
 	case *mongo.Client:
 		mongoClient := c
 		filter := generateMongoDBFilter(field, year)
-		cursor, err := mongoClient.Database("MONGODB_DATABASE").Collection(table).Find(context.Background(), filter)
+		cursor, err := mongoClient.Database("MONGODB_DATABASE").Collection(table).Find(ctx, filter)
 		if err != nil {
 			return err
 		}
-		defer cursor.Close(context.Background())
+		defer cursor.Close(ctx)
 		return nil
 	case *sql.DB:
 		mysqlDB := c
 		query := generateMySQLQuery(table, field, year)
-		rows, err := mysqlDB.Query(query)
+		rows, err := mysqlDB.QueryContext(ctx, query)
 		if err != nil {
 			return err
 		}
@@ -136,24 +195,21 @@ func singleThreadedRead(client interface{}, table, field, year string) error {
 	}
 }
 
-func multiThreadedRead(client interface{}, table, field, year string) error {
-	switch c := client.(type) {
-	case *mongo.Client:
-		mongoClient := c
-		go func() {
-			err := singleThreadedRead(mongoClient, table, field, year)
-			if err != nil {
-				fmt.Printf("Error reading %s in MongoDB: %v\n", table, err)
-			}
-		}()
-		return nil
-	case *sql.DB:
-		mysqlDB := c
+// multiThreadedRead starts the read in the background. pending is done once
+// it finished and done is called with the time it took or its error, unless
+// the run was stopped first.
+func multiThreadedRead(ctx context.Context, client interface{}, table, field string, year int, pending *sync.WaitGroup, done func(time.Duration, error)) error {
+	switch client.(type) {
+	case *mongo.Client, *sql.DB:
+		pending.Add(1)
 		go func() {
-			err := singleThreadedRead(mysqlDB, table, field, year)
-			if err != nil {
-				fmt.Printf("Error reading %s in MySQL: %v\n", table, err)
+			defer pending.Done()
+			start := time.Now()
+			err := runctx.Do(ctx, func(ctx context.Context) error { return singleThreadedRead(ctx, client, table, field, year) })
+			if runctx.Stopped(err) {
+				return
 			}
+			done(time.Since(start), err)
 		}()
 		return nil
 	default:
@@ -161,81 +217,82 @@ func multiThreadedRead(client interface{}, table, field, year string) error {
 	}
 }
 
-func initMongoClient() (*mongo.Client, error) {
-	// Initialize MongoDB client
-	clientOptions := options.Client().ApplyURI("mongodb://localhost:27017")
-	client, err := mongo.Connect(context.Background(), clientOptions)
-	if err != nil {
-		return nil, err
-	}
-
-	// Check the connection
-	err = client.Ping(context.Background(), nil)
-	if err != nil {
-		return nil, err
+func generateMongoDBFilter(field string, year int) interface{} {
+	filter := bson.M{
+		field: year,
 	}
-
-	return client, nil
+	return filter
 }
 
-func initMySQLClient() (*sql.DB, error) {
-	// Initialize MySQL client
-	db, err := sql.Open("mysql", "MYSQL_USERNAME:SQL_PASStcp(localhost:3306)/MYSQL_DATABASE")
-	if err != nil {
-		return nil, err
-	}
+func generateMySQLQuery(table, field string, year int) string {
+	return fmt.Sprintf("SELECT * FROM %s WHERE %s = %d", table, field, year)
+}
 
-	// Check the connection
-	err = db.Ping()
-	if err != nil {
-		return nil, err
+// readQuery is the read of singleThreadedRead as explain runs it
+func readQuery(table, field string, year int) explain.Query {
+	return explain.Query{
+		SQL:   generateMySQLQuery(table, field, year),
+		Mongo: explain.Find(table, generateMongoDBFilter(field, year), nil, 0),
 	}
-
-	return db, nil
 }
 
-func generateMongoDBFilter(field, year string) interface{} {
-	filter := bson.M{
-		field: year,
+// perRow normalises a read time by the number of rows in the table
+func perRow(table string, seconds float64) string {
+	rows, err := dataset.Rows(table)
+	if err != nil || rows == 0 {
+		return ""
 	}
-	return filter
+	perRow := time.Duration(seconds * float64(time.Second) / float64(rows))
+	return fmt.Sprintf("(%v per row, %.0f rows/sec)", perRow, float64(rows)/seconds)
 }
 
-func generateMySQLQuery(table, field, year string) string {
-	return fmt.Sprintf("SELECT * FROM %s WHERE %s = '%s'", table, field, year)
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
 }
 
-func plotTimeBarChart(title string, labels []string, mongoTimes, mysqlTimes []float64) error {
-	p := plot.New()
-
-	p.Title.Text = title
-	p.Y.Label.Text = "Time (s)"
+func mean(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var total float64
+	for _, s := range samples {
+		total += s
+	}
+	return total / float64(len(samples))
+}
 
-	bars1, err := plotter.NewBarChart(plotter.Values(mongoTimes), vg.Points(50))
-	if err != nil {
-		return err
+// filter returns the results of one operation
+func filter(rs []results.Result, operation string) []results.Result {
+	var filtered []results.Result
+	for _, r := range rs {
+		if r.Operation == operation {
+			filtered = append(filtered, r)
+		}
 	}
-	bars1.LineStyle.Width = vg.Length(0)
-	bars1.Color = plotter.DefaultLineStyle.Color
-	bars1.Offset = -vg.Points(25)
-	p.Add(bars1)
+	return filtered
+}
 
-	bars2, err := plotter.NewBarChart(plotter.Values(mysqlTimes), vg.Points(50))
-	if err != nil {
-		return err
+// plotScaling draws read time against the number of rows in each table, one line per backend
+func plotScaling(title string, rs []results.Result) error {
+	var lines []plot.Line
+	for _, backend := range []string{"MongoDB", "MySQL"} {
+		line := plot.Line{Label: backend, Markers: true}
+		for _, r := range rs {
+			if r.Backend == backend && r.Latency.Mean > 0 {
+				line.Points = append(line.Points, plotter.XY{X: float64(r.Rows), Y: r.Latency.Mean / 1e6})
+			}
+		}
+		lines = append(lines, line)
 	}
-	bars2.LineStyle.Width = vg.Length(0)
-	bars2.Color = plotter.DefaultLineStyle.Color
-	bars2.Offset = vg.Points(25)
-	p.Add(bars2)
 
-	p.Legend.Add("MongoDB", bars1)
-	p.Legend.Add("MySQL", bars2)
-	p.NominalX(labels...)
+	return plot.Lines("read_scaling", plot.Chart{Title: title, XLabel: "Rows in table", YLabel: "Mean time per read (s)"}, lines)
+}
 
-	if err := p.Save(8*vg.Inch, 4*vg.Inch, "./PLOTS/plot_read.png"); err != nil {
-		return err
-	}
+func plotTimeBarChart(name, title string, rs []results.Result, metric plot.Metric) error {
+	return plot.Bars(name, title, "Table", rs, label, metric)
+}
 
-	return nil
+// label names the table of a result with its size
+func label(r results.Result) string {
+	return dataset.Label(r.Table)
 }
@@ -0,0 +1,142 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"benchmarkDB/results"
+)
+
+// Limits are the largest regressions accepted by Check, in percent
+type Limits struct {
+	Default      float64
+	PerOperation map[string]float64 // overrides Default for an operation
+}
+
+// For returns the limit of an operation
+func (l Limits) For(operation string) float64 {
+	if limit, ok := l.PerOperation[operation]; ok {
+		return limit
+	}
+	return l.Default
+}
+
+// Check is the verdict on one metric of one result against the baseline
+type Check struct {
+	Baseline   results.Result
+	Current    results.Result
+	Missing    bool    // the result is in the baseline but not in the current run
+	Metric     string  // "p95 latency (ms)", "throughput (ops/sec)", "errors" or "timeouts"
+	Before     float64 // baseline value
+	After      float64 // current value
+	Regression float64 // how much worse the current value is, in percent, negative when better
+	Limit      float64
+	Passed     bool
+}
+
+// singleTiming reports whether a result is one timing of a whole batch of
+// operations, e.g. concurrent inserts, rather than a latency per operation.
+// Its time and throughput vary too much between runs to be gated on.
+func singleTiming(r results.Result) bool {
+	return r.Latency.P95 == 0 && len(r.Samples) == 0
+}
+
+// CheckRegressions compares every result of the baseline with the current run.
+// p95 latency may not grow and throughput may not drop by more than the limit
+// of the operation. Results timed only as a whole are not gated on either.
+// No result may have more errors or timeouts than in the baseline. Results
+// missing from the current run fail.
+func CheckRegressions(baseline, current *results.Run, limits Limits) []Check {
+	deltas, missing, _ := Diff(baseline, current)
+
+	var checks []Check
+	for _, d := range deltas {
+		limit := limits.For(d.Before.Operation)
+		if !singleTiming(d.Before) {
+			// No latency in the current run, e.g. every operation failed,
+			// is a regression
+			checks = append(checks, newCheck(d, "p95 latency (ms)", d.Before.Latency.P95/1000, d.After.Latency.P95/1000, false, limit))
+			if d.Before.Throughput > 0 {
+				checks = append(checks, newCheck(d, "throughput (ops/sec)", d.Before.Throughput, d.After.Throughput, true, limit))
+			}
+		}
+		checks = append(checks,
+			newCountCheck(d, "errors", d.Before.Errors, d.After.Errors),
+			newCountCheck(d, "timeouts", d.Before.Timeouts, d.After.Timeouts),
+		)
+	}
+	for _, r := range missing {
+		checks = append(checks, Check{Baseline: r, Missing: true, Limit: limits.For(r.Operation)})
+	}
+	return checks
+}
+
+func newCheck(d Delta, metric string, before, after float64, higherIsBetter bool, limit float64) Check {
+	c := Check{Baseline: d.Before, Current: d.After, Metric: metric, Before: before, After: after, Limit: limit}
+	switch {
+	case before == 0:
+		c.Passed = true
+	case after == 0:
+		// Nothing was measured, e.g. every operation failed
+		c.Regression = 100
+	case higherIsBetter:
+		c.Regression = (before - after) / before * 100
+	default:
+		c.Regression = (after - before) / before * 100
+	}
+	c.Passed = c.Passed || c.Regression <= limit
+	return c
+}
+
+// newCountCheck checks that a count of failed operations did not grow at all
+func newCountCheck(d Delta, metric string, before, after int64) Check {
+	c := Check{Baseline: d.Before, Current: d.After, Metric: metric, Before: float64(before), After: float64(after)}
+	switch {
+	case after <= before:
+		c.Passed = true
+		if before > 0 {
+			c.Regression = float64(after-before) / float64(before) * 100
+		}
+	case before == 0:
+		c.Regression = 100
+	default:
+		c.Regression = float64(after-before) / float64(before) * 100
+	}
+	return c
+}
+
+// WriteChecks prints a pass/fail line for every check and a verdict, and
+// reports whether all checks passed
+func WriteChecks(w io.Writer, baseline, current *results.Run, checks []Check) (bool, error) {
+	fmt.Fprintf(w, "Checking %s against baseline %s\n\n", current.ID, baseline.ID)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Result\tBackend\tTable\tOperation\tWorkers\tMetric\tBaseline\tCurrent\tRegression\tLimit")
+	failed := 0
+	for _, c := range checks {
+		verdict := "PASS"
+		if !c.Passed || c.Missing {
+			verdict = "FAIL"
+			failed++
+		}
+		r := c.Baseline
+		if c.Missing {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\tmissing from the current run\t\t\t\t%.0f%%\n",
+				verdict, r.Backend, r.Table, r.Operation, r.Workers, c.Limit)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%.3f\t%.3f\t%+.1f%%\t%.0f%%\n",
+			verdict, r.Backend, r.Table, r.Operation, r.Workers, c.Metric, c.Before, c.After, c.Regression, c.Limit)
+	}
+	if err := tw.Flush(); err != nil {
+		return false, err
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(w, "\nFAIL: %d of %d checks regressed beyond their limit\n", failed, len(checks))
+		return false, nil
+	}
+	fmt.Fprintf(w, "\nPASS: all %d checks within their limit\n", len(checks))
+	return true, nil
+}
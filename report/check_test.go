@@ -0,0 +1,64 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"benchmarkDB/results"
+)
+
+// verdicts are whether the checks of each metric passed
+func verdicts(checks []Check) map[string]bool {
+	passed := make(map[string]bool)
+	for _, c := range checks {
+		passed[c.Metric] = c.Passed && !c.Missing
+	}
+	return passed
+}
+
+func TestCheckRegressionsErrorsAndTimeouts(t *testing.T) {
+	result := func(errs, timeouts int64) results.Result {
+		return results.Result{Backend: "MySQL", Table: "table1", Operation: "update", Workers: 1,
+			Operations: 100, Errors: errs, Timeouts: timeouts, DurationSeconds: 1, Throughput: 100}
+	}
+	tests := []struct {
+		before, after    results.Result
+		errors, timeouts bool
+	}{
+		{result(0, 0), result(0, 0), true, true},
+		{result(0, 0), result(1, 0), false, true},
+		{result(0, 0), result(0, 1), true, false},
+		{result(5, 2), result(3, 2), true, true},
+		{result(5, 2), result(6, 3), false, false},
+	}
+	for _, tt := range tests {
+		baseline := &results.Run{Results: []results.Result{tt.before}}
+		current := &results.Run{Results: []results.Result{tt.after}}
+		passed := verdicts(CheckRegressions(baseline, current, Limits{Default: 10}))
+		if passed["errors"] != tt.errors || passed["timeouts"] != tt.timeouts {
+			t.Errorf("%d errors %d timeouts after %d %d: errors passed %v, timeouts passed %v",
+				tt.after.Errors, tt.after.Timeouts, tt.before.Errors, tt.before.Timeouts, passed["errors"], passed["timeouts"])
+		}
+	}
+}
+
+func TestCheckRegressionsSkipsSingleTimings(t *testing.T) {
+	for _, op := range []string{"multi-threaded read", "Multi-threaded", "multi-threaded delete by key"} {
+		before := results.Timing("MongoDB", "table1", op, 100*time.Millisecond, 1)
+		after := results.Timing("MongoDB", "table1", op, 300*time.Millisecond, 1)
+		checks := CheckRegressions(&results.Run{Results: []results.Result{before}}, &results.Run{Results: []results.Result{after}}, Limits{Default: 10})
+		for _, c := range checks {
+			if c.Metric != "errors" && c.Metric != "timeouts" {
+				t.Errorf("%s gated on %s", op, c.Metric)
+			}
+		}
+	}
+
+	// Results with latencies are gated on them
+	before := results.Iterations("MongoDB", "table1", "single-threaded read", []float64{0.001, 0.001, 0.001})
+	after := results.Iterations("MongoDB", "table1", "single-threaded read", []float64{0.003, 0.003, 0.003})
+	passed := verdicts(CheckRegressions(&results.Run{Results: []results.Result{before}}, &results.Run{Results: []results.Result{after}}, Limits{Default: 10}))
+	if passed["p95 latency (ms)"] || passed["throughput (ops/sec)"] {
+		t.Errorf("tripling the latency passed: %v", passed)
+	}
+}
@@ -0,0 +1,154 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"benchmarkDB/results"
+	"benchmarkDB/stats"
+)
+
+// Delta is the change of one result between two runs
+type Delta struct {
+	Before, After results.Result
+	Test          stats.Test
+	Tested        bool // false when neither samples nor a standard deviation were recorded
+}
+
+// Diff pairs the results of two runs by backend, table, operation, worker
+// count and pool size. Results found in only one of the runs are returned
+// separately.
+func Diff(before, after *results.Run) (deltas []Delta, onlyBefore, onlyAfter []results.Result) {
+	key := func(r results.Result) string {
+		return fmt.Sprintf("%s/%s/%s/%d/%d", r.Backend, r.Table, r.Operation, r.Workers, r.PoolSize)
+	}
+	afterByKey := make(map[string]results.Result)
+	for _, r := range after.Results {
+		afterByKey[key(r)] = r
+	}
+
+	matched := make(map[string]bool)
+	for _, b := range before.Results {
+		a, ok := afterByKey[key(b)]
+		if !ok {
+			onlyBefore = append(onlyBefore, b)
+			continue
+		}
+		matched[key(b)] = true
+		test, tested := Significance(b, a)
+		deltas = append(deltas, Delta{Before: b, After: a, Test: test, Tested: tested})
+	}
+	for _, a := range after.Results {
+		if !matched[key(a)] {
+			onlyAfter = append(onlyAfter, a)
+		}
+	}
+	return deltas, onlyBefore, onlyAfter
+}
+
+// Significance tests whether the latencies of two results differ. Results
+// with per-iteration samples use the Mann-Whitney U test, others Welch's t
+// test on their mean and standard deviation.
+func Significance(a, b results.Result) (stats.Test, bool) {
+	if len(a.Samples) >= 2 && len(b.Samples) >= 2 {
+		return stats.MannWhitney(a.Samples, b.Samples), true
+	}
+	if a.Operations >= 2 && b.Operations >= 2 && (a.Latency.StdDev > 0 || b.Latency.StdDev > 0) {
+		return stats.Welch(a.Latency.Mean, a.Latency.StdDev, a.Operations, b.Latency.Mean, b.Latency.StdDev, b.Operations), true
+	}
+	return stats.Test{}, false
+}
+
+// WriteDiff prints the changes from one run to another: the configuration
+// and environment fields that differ and, for every result, p50, p99 and
+// throughput before and after with a mark on significant changes
+func WriteDiff(w io.Writer, before, after *results.Run) error {
+	fmt.Fprintf(w, "Comparing %s (%s) with %s (%s)\n", before.ID, before.Started.Format("2006-01-02 15:04"),
+		after.ID, after.Started.Format("2006-01-02 15:04"))
+	writeFieldChanges(w, "Configuration", Fields(before.Config), Fields(after.Config))
+	writeFieldChanges(w, "Environment", EnvironmentFields(before.Environment), EnvironmentFields(after.Environment))
+
+	deltas, onlyBefore, onlyAfter := Diff(before, after)
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Backend\tTable\tOperation\tWorkers\tp50 before (ms)\tp50 after (ms)\tchange\tp99 change\tops/sec before\tops/sec after\tchange\tp-value\t\t")
+	significant := 0
+	for _, d := range deltas {
+		mark, p := "", "-"
+		if d.Tested {
+			p = fmt.Sprintf("%.3f", d.Test.P)
+			if d.Test.Significant() {
+				mark = "*"
+				significant++
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%.2f\t%.2f\t%s\t%s\t%s\t\n",
+			d.Before.Backend, d.Before.Table, d.Before.Operation, d.Before.Workers,
+			millis(d.Before.Latency.P50), millis(d.After.Latency.P50),
+			change(d.Before.Latency.P50, d.After.Latency.P50), change(d.Before.Latency.P99, d.After.Latency.P99),
+			d.Before.Throughput, d.After.Throughput, change(d.Before.Throughput, d.After.Throughput), p, mark)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\n%d of %d results changed significantly (* p < %.2f)\n", significant, len(deltas), stats.Alpha)
+
+	for _, r := range onlyBefore {
+		fmt.Fprintf(w, "Only in %s: %s %s %s (%d workers)\n", before.ID, r.Backend, r.Table, r.Operation, r.Workers)
+	}
+	for _, r := range onlyAfter {
+		fmt.Fprintf(w, "Only in %s: %s %s %s (%d workers)\n", after.ID, r.Backend, r.Table, r.Operation, r.Workers)
+	}
+	return nil
+}
+
+func writeFieldChanges(w io.Writer, title string, before, after []Field) {
+	values := make(map[string]string)
+	for _, f := range before {
+		values[f.Name] = f.Value
+	}
+	seen := make(map[string]bool)
+	var changes []string
+	for _, f := range after {
+		seen[f.Name] = true
+		if values[f.Name] != f.Value {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", f.Name, orNone(values[f.Name]), f.Value))
+		}
+	}
+	for _, f := range before {
+		if !seen[f.Name] {
+			changes = append(changes, fmt.Sprintf("%s: %s -> none", f.Name, f.Value))
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s changes:\n", title)
+	for _, c := range changes {
+		fmt.Fprintln(w, "  "+c)
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// millis formats a latency in microseconds as milliseconds, "-" when not measured
+func millis(us float64) string {
+	if us == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.3f", us/1000)
+}
+
+// change of after from before in percent
+func change(before, after float64) string {
+	if before == 0 || after == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", (after-before)/before*100)
+}
@@ -0,0 +1,320 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"benchmarkDB/dataset"
+	"benchmarkDB/report/plot"
+	"benchmarkDB/results"
+
+	"gonum.org/v1/plot/plotter"
+)
+
+// section is the table and charts of one operation
+type section struct {
+	Operation   string
+	Results     []results.Result
+	Comparisons []Comparison
+	Charts      []template.HTML
+}
+
+// HTML writes a single page report of the run with the charts embedded as
+// SVG, so it can be viewed offline and shared as one file
+func HTML(w io.Writer, run *results.Run) error {
+	page := struct {
+		Run         *results.Run
+		Elapsed     time.Duration
+		Config      []Field
+		Environment []Field
+		Comparisons []Comparison
+		Sections    []section
+		Series      []template.HTML
+		Writes      []results.Result
+		Failures    []results.Result
+		Errors      []results.Error
+	}{
+		Run:         run,
+		Elapsed:     run.Finished.Sub(run.Started).Round(time.Millisecond),
+		Config:      Fields(run.Config),
+		Environment: EnvironmentFields(run.Environment),
+		Comparisons: Compare(run),
+		Writes:      Writes(run),
+		Failures:    Failures(run),
+		Errors:      run.Errors,
+	}
+
+	for _, operation := range Operations(run) {
+		rs := filter(run.Results, operation)
+		s := section{Operation: operation, Results: rs}
+		for _, c := range page.Comparisons {
+			if c.Operation == operation {
+				s.Comparisons = append(s.Comparisons, c)
+			}
+		}
+		charts, err := plot.CaptureSVG(func() error { return operationCharts(operation, operation, rs) })
+		if err != nil {
+			return err
+		}
+		s.Charts = inline(charts)
+		page.Sections = append(page.Sections, s)
+	}
+
+	if len(run.Series) > 0 {
+		charts, err := plot.CaptureSVG(func() error { return seriesChart(run.Series) })
+		if err != nil {
+			return err
+		}
+		page.Series = inline(charts)
+	}
+
+	return htmlTemplate.Execute(w, page)
+}
+
+// operationCharts draws the latency or time and the throughput of each backend
+// per table, and the latency distributions when the results kept samples.
+// Chart names start with name. Charts without data are left out, any other
+// error is returned.
+func operationCharts(name, operation string, rs []results.Result) error {
+	metric := headlineMetric(rs)
+	return errors.Join(
+		skipNoData(plot.Bars(name, operation+": "+metric.Label, "Table", rs, category, metric)),
+		skipNoData(plot.Bars(name+"_throughput", operation+": throughput", "Table", rs, category, plot.Throughput)),
+		skipNoData(plot.Distributions(name, operation, rs, category)),
+	)
+}
+
+// skipNoData drops the error of a chart left out for lack of data
+func skipNoData(err error) error {
+	if errors.Is(err, plot.ErrNoData) {
+		return nil
+	}
+	return err
+}
+
+// headlineMetric is p50 latency when every result has one and the total time
+// taken otherwise, the same way comparisons pick the winner
+func headlineMetric(rs []results.Result) plot.Metric {
+	for _, r := range rs {
+		if r.Latency.P50 == 0 {
+			return plot.Duration
+		}
+	}
+	return plot.P50
+}
+
+// seriesChart draws the throughput over time of every sampled series
+func seriesChart(series []results.Series) error {
+	var lines []plot.Line
+	for _, s := range series {
+		line := plot.Line{Label: fmt.Sprintf("%s %s w%d", s.Backend, s.Table, s.Workers)}
+		for _, sample := range s.Samples {
+			line.Points = append(line.Points, plotter.XY{X: sample.ElapsedSeconds, Y: sample.Throughput})
+		}
+		lines = append(lines, line)
+	}
+	chart := plot.Chart{Title: "Throughput over time", XLabel: "Elapsed time (s)", YLabel: "Throughput (ops/sec)"}
+	return skipNoData(plot.Lines("throughput_over_time", chart, lines))
+}
+
+// category names the table of a result with its size and the number of workers
+func category(r results.Result) string {
+	label := dataset.Label(r.Table)
+	if r.Workers > 1 {
+		label += fmt.Sprintf(", %d workers", r.Workers)
+	}
+	return label
+}
+
+func inline(charts []plot.Rendered) []template.HTML {
+	svgs := make([]template.HTML, len(charts))
+	for i, c := range charts {
+		// The SVG is drawn by the plot package, not taken from the results file.
+		// The XML declaration is dropped as it is not allowed inside HTML.
+		svg := bytes.TrimSpace(c.SVG)
+		if bytes.HasPrefix(svg, []byte("<?xml")) {
+			svg = svg[bytes.Index(svg, []byte("?>"))+2:]
+		}
+		svgs[i] = template.HTML(svg)
+	}
+	return svgs
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms":    func(us float64) string { return fmt.Sprintf("%.3f", us/1000) },
+	"fixed": func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"table": dataset.Label,
+	"interval": func(r results.Result) string {
+		ci, ok := ConfidenceInterval(r)
+		if !ok {
+			return "-"
+		}
+		return fmt.Sprintf("%.3f-%.3f", ci.Low/1000, ci.High/1000)
+	},
+	"time": func(t time.Time) string { return t.Format("2006-01-02 15:04:05 MST") },
+	"serverMetrics": func(rs []results.Result) bool {
+		for _, r := range rs {
+			if len(r.Server) > 0 {
+				return true
+			}
+		}
+		return false
+	},
+	"plans": func(rs []results.Result) bool {
+		for _, r := range rs {
+			if r.Plan != nil {
+				return true
+			}
+		}
+		return false
+	},
+	"pools": func(rs []results.Result) bool {
+		for _, r := range rs {
+			if r.Pool != nil {
+				return true
+			}
+		}
+		return false
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Run.ID}}</title>
+<style>
+body { font-family: sans-serif; max-width: 1100px; margin: 2em auto; padding: 0 1em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child, td.text { text-align: left; }
+th { background: #f4f4f4; }
+svg { width: 100%; height: auto; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>{{.Run.Benchmark}} benchmark</h1>
+<p>Run <code>{{.Run.ID}}</code>, started {{time .Run.Started}}, took {{.Elapsed}}.</p>
+{{- if .Run.Stopped}}
+<p class="error">The run stopped early ({{.Run.Stopped}}), the results are partial.</p>
+{{- end}}
+
+<h2>Summary</h2>
+<ul>
+{{- range .Comparisons}}
+<li><b>{{.Label}}</b>: {{.Summary}}</li>
+{{- end}}
+</ul>
+{{- if or .Failures .Errors}}
+<p class="error">Operations failed during the run, see <a href="#errors">Errors</a>.</p>
+{{- end}}
+
+<h2>Configuration</h2>
+{{- if .Config}}
+<table>
+{{- range .Config}}
+<tr><th>{{.Name}}</th><td class="text">{{.Value}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>Default settings.</p>
+{{- end}}
+
+<h2>Environment</h2>
+<table>
+{{- range .Environment}}
+<tr><th>{{.Name}}</th><td class="text">{{.Value}}</td></tr>
+{{- end}}
+</table>
+
+{{- range .Sections}}
+<h2>{{.Operation}}</h2>
+<ul>
+{{- range .Comparisons}}
+<li>{{.Label}}: {{.Summary}}</li>
+{{- end}}
+</ul>
+<table>
+<tr><th>Backend</th><th>Table</th><th>Workers</th><th>Operations</th><th>Errors</th><th>Time (s)</th><th>Throughput (ops/sec)</th><th>Mean (ms)</th><th>p50 (ms)</th><th>95% CI (ms)</th><th>p95 (ms)</th><th>p99 (ms)</th><th>Max (ms)</th></tr>
+{{- range .Results}}
+<tr><td>{{.Backend}}</td><td class="text">{{table .Table}}{{if .PoolSize}}, pool of {{.PoolSize}}{{end}}</td><td>{{.Workers}}</td><td>{{.Operations}}</td><td{{if .Errors}} class="error"{{end}}>{{.Errors}}</td><td>{{fixed .DurationSeconds}}</td><td>{{fixed .Throughput}}</td><td>{{ms .Latency.Mean}}</td><td>{{ms .Latency.P50}}</td><td>{{interval .}}</td><td>{{ms .Latency.P95}}</td><td>{{ms .Latency.P99}}</td><td>{{ms .Latency.Max}}</td></tr>
+{{- end}}
+</table>
+{{- if serverMetrics .Results}}
+<table>
+<tr><th>Backend</th><th>Table</th><th>Server metrics</th></tr>
+{{- range .Results}}
+{{- if .Server}}
+<tr><td>{{.Backend}}</td><td class="text">{{table .Table}}</td><td class="text">{{.Server}}</td></tr>
+{{- end}}
+{{- end}}
+</table>
+{{- end}}
+{{- if plans .Results}}
+<table>
+<tr><th>Backend</th><th>Table</th><th>Query plan</th></tr>
+{{- range .Results}}
+{{- if .Plan}}
+<tr><td>{{.Backend}}</td><td class="text">{{table .Table}}</td><td class="text" title="{{.Plan.Query}}">{{.Plan}}</td></tr>
+{{- end}}
+{{- end}}
+</table>
+{{- end}}
+{{- if pools .Results}}
+<table>
+<tr><th>Backend</th><th>Table</th><th>Connections</th></tr>
+{{- range .Results}}
+{{- if .Pool}}
+<tr><td>{{.Backend}}</td><td class="text">{{table .Table}}</td><td class="text">{{.Pool}}</td></tr>
+{{- end}}
+{{- end}}
+</table>
+{{- end}}
+{{- range .Charts}}
+{{.}}
+{{- end}}
+{{- end}}
+
+{{- if .Series}}
+<h2>Throughput over time</h2>
+{{- range .Series}}
+{{.}}
+{{- end}}
+{{- end}}
+
+{{- if .Writes}}
+<h2>Rows written</h2>
+<table>
+<tr><th>Backend</th><th>Table</th><th>Operation</th><th>Matched</th><th>Modified</th><th>Upserted</th></tr>
+{{- range .Writes}}
+<tr><td>{{.Backend}}</td><td class="text">{{table .Table}}</td><td class="text">{{.Operation}}</td><td>{{.Matched}}</td><td>{{.Modified}}</td><td>{{.Upserted}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+<h2 id="errors">Errors</h2>
+{{- if .Failures}}
+<table>
+<tr><th>Backend</th><th>Table</th><th>Operation</th><th>Succeeded</th><th>Failed</th><th>Timed out</th></tr>
+{{- range .Failures}}
+<tr><td>{{.Backend}}</td><td class="text">{{table .Table}}</td><td class="text">{{.Operation}}</td><td>{{.Successes}}</td><td{{if .Errors}} class="error"{{end}}>{{.Errors}}</td><td{{if .Timeouts}} class="error"{{end}}>{{.Timeouts}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Errors}}
+<table>
+<tr><th>Backend</th><th>Table</th><th>Operation</th><th>Count</th><th>Error</th></tr>
+{{- range .Errors}}
+<tr><td>{{.Backend}}</td><td class="text">{{table .Table}}</td><td class="text">{{.Operation}}</td><td class="error">{{.Count}}</td><td class="text">{{.Message}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if not (or .Failures .Errors)}}
+<p>No errors were recorded.</p>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,30 @@
+package report
+
+import (
+	"math"
+	"testing"
+
+	"benchmarkDB/report/plot"
+	"benchmarkDB/results"
+)
+
+func TestOperationChartsSkipsOnlyMissingData(t *testing.T) {
+	// Without samples there are no distributions, which is not an error
+	rs := []results.Result{
+		{Backend: "MongoDB", Table: "table1", Operation: "read", DurationSeconds: 1, Throughput: 10},
+		{Backend: "MySQL", Table: "table1", Operation: "read", DurationSeconds: 2, Throughput: 5},
+	}
+	charts, err := plot.CaptureSVG(func() error { return operationCharts("read", "read", rs) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(charts) != 2 || charts[0].Name != "read" || charts[1].Name != "read_throughput" {
+		t.Errorf("drew %d charts, want the time and throughput", len(charts))
+	}
+
+	// A chart that cannot be drawn is
+	rs[1].Throughput = math.NaN()
+	if _, err := plot.CaptureSVG(func() error { return operationCharts("read", "read", rs) }); err == nil {
+		t.Error("a throughput of NaN was plotted without an error")
+	}
+}
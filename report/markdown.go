@@ -0,0 +1,275 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"benchmarkDB/report/plot"
+	"benchmarkDB/results"
+	"benchmarkDB/stats"
+)
+
+// Markdown writes a summary of the run with a comparison table per operation,
+// for pasting into pull requests or committing next to the code. The charts
+// of each operation are written to the plots directory and linked relative
+// to dir, the directory the summary is saved in.
+func Markdown(w io.Writer, run *results.Run, dir string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s benchmark\n\n", run.Benchmark)
+	fmt.Fprintf(&b, "Run `%s`, started %s, took %v.\n", run.ID,
+		run.Started.Format("2006-01-02 15:04:05 MST"), run.Finished.Sub(run.Started).Round(time.Millisecond))
+	if run.Stopped != "" {
+		fmt.Fprintf(&b, "\n**The run stopped early (%s), the results are partial.**\n", run.Stopped)
+	}
+
+	if config := Fields(run.Config); len(config) > 0 {
+		b.WriteString("\nConfiguration: ")
+		writeFields(&b, config)
+	}
+	b.WriteString("\nEnvironment: ")
+	writeFields(&b, EnvironmentFields(run.Environment))
+
+	comparisons := Compare(run)
+	for _, operation := range Operations(run) {
+		fmt.Fprintf(&b, "\n## %s\n\n", operation)
+
+		var group []Comparison
+		for _, c := range comparisons {
+			if c.Operation == operation {
+				group = append(group, c)
+			}
+		}
+		writeComparisonTable(&b, group)
+		writeServerMetrics(&b, group)
+		writePlans(&b, group)
+		writePools(&b, group)
+
+		name := chartName(run.ID, operation)
+		rs := filter(run.Results, operation)
+		charts, err := plot.Record(func() error { return operationCharts(name, operation, rs) })
+		if err != nil {
+			return err
+		}
+		if len(charts) > 0 {
+			b.WriteString("\nCharts:")
+			for _, c := range charts {
+				label := strings.TrimPrefix(c.Name, name+"_")
+				if c.Name == name {
+					label = strings.ToLower(headlineMetric(rs).Label)
+				}
+				fmt.Fprintf(&b, " [%s](%s)", label, link(dir, c.Path))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if writes := Writes(run); len(writes) > 0 {
+		b.WriteString("\n## Rows written\n\n| Backend | Table | Operation | Matched | Modified | Upserted |\n|---|---|---|--:|--:|--:|\n")
+		for _, r := range writes {
+			fmt.Fprintf(&b, "| %s | %s | %s | %d | %d | %d |\n", r.Backend, r.Table, r.Operation, r.Matched, r.Modified, r.Upserted)
+		}
+	}
+
+	failures := Failures(run)
+	if len(failures) > 0 || len(run.Errors) > 0 {
+		b.WriteString("\n## Errors\n")
+	}
+	if len(failures) > 0 {
+		b.WriteString("\n| Backend | Table | Operation | Succeeded | Failed | Timed out |\n|---|---|---|--:|--:|--:|\n")
+		for _, r := range failures {
+			fmt.Fprintf(&b, "| %s | %s | %s | %d | %d | %d |\n", r.Backend, r.Table, r.Operation, r.Successes(), r.Errors, r.Timeouts)
+		}
+	}
+	if len(run.Errors) > 0 {
+		b.WriteString("\n| Backend | Table | Operation | Count | Error |\n|---|---|---|--:|---|\n")
+		for _, e := range run.Errors {
+			fmt.Fprintf(&b, "| %s | %s | %s | %d | %s |\n", e.Backend, e.Table, e.Operation, e.Count, strings.ReplaceAll(e.Message, "|", "\\|"))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeComparisonTable writes p50, p95 and throughput of every backend side
+// by side, with the difference of each backend from the first one
+func writeComparisonTable(b *strings.Builder, group []Comparison) {
+	var backends []string
+	for _, c := range group {
+		for _, r := range c.Results {
+			if !slices.Contains(backends, r.Backend) {
+				backends = append(backends, r.Backend)
+			}
+		}
+	}
+
+	metrics := []struct {
+		label string
+		value func(results.Result) float64
+	}{
+		{"p50 (ms)", func(r results.Result) float64 { return r.Latency.P50 / 1000 }},
+		{"p95 (ms)", func(r results.Result) float64 { return r.Latency.P95 / 1000 }},
+		{"ops/sec", func(r results.Result) float64 { return r.Throughput }},
+	}
+
+	b.WriteString("| Table |")
+	align := "|---|"
+	for _, m := range metrics {
+		for i, backend := range backends {
+			fmt.Fprintf(b, " %s %s |", backend, m.label)
+			align += "--:|"
+			if i > 0 {
+				fmt.Fprintf(b, " %s vs %s |", backend, backends[0])
+				align += "--:|"
+			}
+		}
+	}
+	b.WriteString(" Winner |\n" + align + "---|\n")
+
+	for _, c := range group {
+		byBackend := make(map[string]results.Result)
+		for _, r := range c.Results {
+			byBackend[r.Backend] = r
+		}
+
+		fmt.Fprintf(b, "| %s |", c.Where())
+		for _, m := range metrics {
+			base, hasBase := byBackend[backends[0]]
+			for i, backend := range backends {
+				r, ok := byBackend[backend]
+				fmt.Fprintf(b, " %s |", cell(m.value(r), ok))
+				if i > 0 {
+					fmt.Fprintf(b, " %s |", difference(m.value(base), m.value(r), ok && hasBase))
+				}
+			}
+		}
+		fmt.Fprintf(b, " %s |\n", winner(c))
+	}
+
+	var intervals []string
+	for _, c := range group {
+		for _, r := range c.Results {
+			if ci, ok := ConfidenceInterval(r); ok {
+				intervals = append(intervals, fmt.Sprintf("%s %s %.3f [%.3f, %.3f]", c.Where(), r.Backend, ci.Estimate/1000, ci.Low/1000, ci.High/1000))
+			}
+		}
+	}
+	if len(intervals) > 0 {
+		fmt.Fprintf(b, "\n%.0f%% confidence intervals of the latency (ms): %s\n", stats.Confidence*100, strings.Join(intervals, "; "))
+	}
+}
+
+// writeServerMetrics lists the server status changes of every backend while
+// the operation ran, when they were collected
+func writeServerMetrics(b *strings.Builder, group []Comparison) {
+	var lines []string
+	for _, c := range group {
+		for _, r := range c.Results {
+			if len(r.Server) > 0 {
+				lines = append(lines, fmt.Sprintf("- %s %s: %s\n", c.Where(), r.Backend, r.Server))
+			}
+		}
+	}
+	if len(lines) > 0 {
+		b.WriteString("\nServer metrics:\n\n" + strings.Join(lines, ""))
+	}
+}
+
+// writePlans lists how every backend ran the query of the operation, when it
+// was explained
+func writePlans(b *strings.Builder, group []Comparison) {
+	var lines []string
+	for _, c := range group {
+		for _, r := range c.Results {
+			if r.Plan != nil {
+				lines = append(lines, fmt.Sprintf("- %s %s: %s\n", c.Where(), r.Backend, r.Plan))
+			}
+		}
+	}
+	if len(lines) > 0 {
+		b.WriteString("\nQuery plans:\n\n" + strings.Join(lines, ""))
+	}
+}
+
+// writePools lists the connection checkouts of every backend while the
+// operation ran, when they were counted
+func writePools(b *strings.Builder, group []Comparison) {
+	var lines []string
+	for _, c := range group {
+		for _, r := range c.Results {
+			if r.Pool != nil {
+				lines = append(lines, fmt.Sprintf("- %s %s: %s\n", c.Where(), r.Backend, r.Pool))
+			}
+		}
+	}
+	if len(lines) > 0 {
+		b.WriteString("\nConnection pools:\n\n" + strings.Join(lines, ""))
+	}
+}
+
+// cell formats a measured value, "-" when the backend has none
+func cell(v float64, ok bool) string {
+	if !ok || v == 0 {
+		return "-"
+	}
+	if v >= 100 {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.3f", v)
+}
+
+// difference of v from base in percent
+func difference(base, v float64, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return change(base, v)
+}
+
+func winner(c Comparison) string {
+	switch {
+	case c.Winner == "":
+		return "-"
+	case c.Tested && !c.Test.Significant():
+		return fmt.Sprintf("no significant difference (p=%.3f)", c.Test.P)
+	case c.Tested:
+		return fmt.Sprintf("%s by %.0f%% (p=%.3f)", c.Winner, c.Margin, c.Test.P)
+	case c.Margin < 1:
+		return "tie"
+	default:
+		return fmt.Sprintf("%s by %.0f%% (%s, not tested)", c.Winner, c.Margin, c.Metric)
+	}
+}
+
+func writeFields(b *strings.Builder, fields []Field) {
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(b, "%s `%s`", f.Name, f.Value)
+	}
+	b.WriteString("\n")
+}
+
+// chartName is the file name prefix of the charts of an operation, e.g.
+// "read-20240101-120000-482913_single-threaded_read"
+func chartName(id, operation string) string {
+	return id + "_" + strings.Map(func(r rune) rune {
+		if r == ' ' || r == '/' || r == '(' || r == ')' {
+			return '_'
+		}
+		return r
+	}, operation)
+}
+
+// link is the path of a chart relative to dir, with forward slashes
+func link(dir, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
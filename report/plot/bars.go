@@ -0,0 +1,82 @@
+package plot
+
+import (
+	"slices"
+
+	"benchmarkDB/results"
+
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// GroupedBars draws one group of bars per category with a bar for each series
+func GroupedBars(name string, chart Chart, categories []string, series []Series) error {
+	if len(series) == 0 {
+		return ErrNoData
+	}
+	p := newPlot(chart)
+
+	width := vg.Points(60 / float64(len(series)))
+	for i, s := range series {
+		values := make(plotter.Values, len(categories))
+		copy(values, s.Values)
+
+		bars, err := plotter.NewBarChart(values, width)
+		if err != nil {
+			return err
+		}
+		bars.LineStyle.Width = vg.Length(0)
+		bars.Color = Color(s.Label, i)
+		bars.Offset = width * vg.Length(float64(i)-float64(len(series)-1)/2)
+		p.Add(bars)
+		p.Legend.Add(s.Label, bars)
+	}
+	nominalAxis(p, categories)
+
+	return save(p, name)
+}
+
+// Metric is a value plotted for each result
+type Metric struct {
+	Label string
+	Value func(results.Result) float64
+}
+
+// Metrics shared by the charts of every benchmark
+var (
+	Duration   = Metric{"Time (s)", func(r results.Result) float64 { return r.DurationSeconds }}
+	Mean       = Metric{"Mean time per operation (ms)", func(r results.Result) float64 { return r.Latency.Mean / 1000 }}
+	Throughput = Metric{"Throughput (ops/sec)", func(r results.Result) float64 { return r.Throughput }}
+	P50        = Metric{"p50 latency (ms)", func(r results.Result) float64 { return r.Latency.P50 / 1000 }}
+	P99        = Metric{"p99 latency (ms)", func(r results.Result) float64 { return r.Latency.P99 / 1000 }}
+)
+
+// Bars draws a grouped bar chart of results, with one group per category
+// (e.g. the table or the operation) and one bar per backend
+func Bars(name, title, xLabel string, rs []results.Result, category func(results.Result) string, metric Metric) error {
+	var categories []string
+	var backends []string
+	index := make(map[string]int)
+	for _, r := range rs {
+		c := category(r)
+		if _, ok := index[c]; !ok {
+			index[c] = len(categories)
+			categories = append(categories, c)
+		}
+		if !slices.Contains(backends, r.Backend) {
+			backends = append(backends, r.Backend)
+		}
+	}
+
+	series := make([]Series, len(backends))
+	for i, backend := range backends {
+		series[i] = Series{Label: backend, Values: make([]float64, len(categories))}
+		for _, r := range rs {
+			if r.Backend == backend {
+				series[i].Values[index[category(r)]] = metric.Value(r)
+			}
+		}
+	}
+
+	return GroupedBars(name, Chart{Title: title, XLabel: xLabel, YLabel: metric.Label}, categories, series)
+}
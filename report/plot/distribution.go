@@ -0,0 +1,331 @@
+package plot
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"benchmarkDB/results"
+	"benchmarkDB/stats"
+
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// swatch is a legend entry filled with a single colour
+type swatch struct {
+	color color.Color
+}
+
+func (s swatch) Thumbnail(c *draw.Canvas) {
+	c.FillPolygon(s.color, c.ClipPolygonY([]vg.Point{
+		{X: c.Min.X, Y: c.Min.Y}, {X: c.Min.X, Y: c.Max.Y},
+		{X: c.Max.X, Y: c.Max.Y}, {X: c.Max.X, Y: c.Min.Y},
+	}))
+}
+
+// Box draws a box plot of the samples of each series per category, boxes of
+// the same category side by side
+func Box(name string, chart Chart, categories []string, series []Series) error {
+	if len(series) == 0 {
+		return ErrNoData
+	}
+	p := newPlot(chart)
+
+	width := vg.Points(60 / float64(len(series)))
+	drawn := 0
+	for i, s := range series {
+		// Every series with a box gets a legend entry, once
+		inLegend := false
+		for c := range categories {
+			if c >= len(s.Samples) || len(s.Samples[c]) == 0 {
+				continue
+			}
+			box, err := plotter.NewBoxPlot(width, float64(c), plotter.Values(s.Samples[c]))
+			if err != nil {
+				return err
+			}
+			box.Offset = width * vg.Length(float64(i)-float64(len(series)-1)/2)
+			box.FillColor = Color(s.Label, i)
+			p.Add(box)
+			drawn++
+			if !inLegend {
+				p.Legend.Add(s.Label, swatch{box.FillColor})
+				inLegend = true
+			}
+		}
+	}
+	if drawn == 0 {
+		return ErrNoData
+	}
+	nominalAxis(p, categories)
+
+	return save(p, name)
+}
+
+// CDF draws the cumulative distribution of the samples of each series and
+// marks the given quantiles (e.g. 0.5, 0.99) on every line
+func CDF(name string, chart Chart, series []Series, quantiles ...float64) error {
+	if chart.YLabel == "" {
+		chart.YLabel = "Cumulative fraction"
+	}
+	p := newPlot(chart)
+
+	drawn := 0
+	for i, s := range series {
+		if len(s.Values) == 0 {
+			continue
+		}
+		sorted := append([]float64(nil), s.Values...)
+		sort.Float64s(sorted)
+
+		line, err := plotter.NewLine(cdfPoints(sorted))
+		if err != nil {
+			return err
+		}
+		line.Color = Color(s.Label, i)
+		p.Add(line)
+		p.Legend.Add(s.Label, line)
+		drawn++
+
+		if len(quantiles) == 0 {
+			continue
+		}
+		markers := make(plotter.XYs, len(quantiles))
+		labels := make([]string, len(quantiles))
+		for j, q := range quantiles {
+			markers[j] = plotter.XY{X: stats.Quantile(sorted, q), Y: q}
+			labels[j] = quantileLabel(q)
+		}
+		scatter, err := plotter.NewScatter(markers)
+		if err != nil {
+			return err
+		}
+		scatter.Color = line.Color
+		scatter.Shape = draw.CircleGlyph{}
+		p.Add(scatter)
+
+		text, err := plotter.NewLabels(plotter.XYLabels{XYs: markers, Labels: labels})
+		if err != nil {
+			return err
+		}
+		for j := range text.TextStyle {
+			text.TextStyle[j].Color = line.Color
+			text.TextStyle[j].XAlign = draw.XRight
+		}
+		text.Offset = vg.Point{X: -4, Y: 2}
+		p.Add(text)
+	}
+	if drawn == 0 {
+		return ErrNoData
+	}
+	p.Y.Min = 0
+	p.Y.Max = 1.05
+
+	return save(p, name)
+}
+
+// quantileLabel formats 0.99 as "p99" and 0.999 as "p99.9"
+func quantileLabel(q float64) string {
+	return "p" + strconv.FormatFloat(q*100, 'f', -1, 64)
+}
+
+// Histogram draws the distribution of the samples of each series as the
+// outline of a histogram, so the series can overlap. Bins are spaced
+// logarithmically when the samples span more than a decade, with the
+// fraction of samples in each bin on the y axis.
+func Histogram(name string, chart Chart, series []Series, bins int) error {
+	lowest, highest := math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for _, v := range s.Values {
+			lowest = math.Min(lowest, v)
+			highest = math.Max(highest, v)
+		}
+	}
+	if math.IsInf(lowest, 0) {
+		return ErrNoData
+	}
+	if highest == lowest {
+		highest = lowest + 1
+	}
+	chart.LogX = lowest > 0 && highest/lowest > 10
+	edges := binEdges(lowest, highest, bins, chart.LogX)
+	if chart.YLabel == "" {
+		chart.YLabel = "Fraction of samples"
+	}
+
+	var lines []Line
+	for _, s := range series {
+		if len(s.Values) == 0 {
+			continue
+		}
+		counts := make([]float64, bins)
+		for _, v := range s.Values {
+			b := sort.SearchFloat64s(edges, v) - 1
+			if b < 0 {
+				b = 0
+			}
+			if b >= bins {
+				b = bins - 1
+			}
+			counts[b]++
+		}
+
+		line := Line{Label: s.Label, Points: plotter.XYs{{X: edges[0], Y: 0}}}
+		for b, c := range counts {
+			f := c / float64(len(s.Values))
+			line.Points = append(line.Points, plotter.XY{X: edges[b], Y: f}, plotter.XY{X: edges[b+1], Y: f})
+		}
+		line.Points = append(line.Points, plotter.XY{X: edges[bins], Y: 0})
+		lines = append(lines, line)
+	}
+	return Lines(name, chart, lines)
+}
+
+// binEdges splits [lowest, highest] into bins, returning bins+1 edges
+func binEdges(lowest, highest float64, bins int, logarithmic bool) []float64 {
+	edges := make([]float64, bins+1)
+	for i := range edges {
+		f := float64(i) / float64(bins)
+		if logarithmic {
+			edges[i] = lowest * math.Pow(highest/lowest, f)
+		} else {
+			edges[i] = lowest + (highest-lowest)*f
+		}
+	}
+	return edges
+}
+
+// Distributions draws a box plot of the per-iteration latencies of every
+// result, one box per backend and category, and a latency histogram and CDF
+// for each table. Results without samples are skipped.
+func Distributions(name, title string, rs []results.Result, category func(results.Result) string) error {
+	var categories, tables, backends []string
+	for _, r := range rs {
+		if len(r.Samples) == 0 {
+			continue
+		}
+		if !slices.Contains(categories, category(r)) {
+			categories = append(categories, category(r))
+		}
+		if !slices.Contains(tables, r.Table) {
+			tables = append(tables, r.Table)
+		}
+		if !slices.Contains(backends, r.Backend) {
+			backends = append(backends, r.Backend)
+		}
+	}
+	if len(categories) == 0 {
+		return fmt.Errorf("%w: no per-iteration latencies", ErrNoData)
+	}
+
+	boxes := make([]Series, len(backends))
+	for i, backend := range backends {
+		boxes[i] = Series{Label: backend, Samples: make([][]float64, len(categories))}
+		for _, r := range rs {
+			if r.Backend == backend && len(r.Samples) > 0 {
+				c := indexOf(categories, category(r))
+				boxes[i].Samples[c] = append(boxes[i].Samples[c], millis(r.Samples)...)
+			}
+		}
+	}
+	chart := Chart{Title: title + " latency distribution", YLabel: "Latency (ms)"}
+	if err := Box(name+"_box", chart, categories, boxes); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		// Each series is labelled with the categories of its own results
+		var series []Series
+		var labels []string
+		for _, backend := range backends {
+			s := Series{Label: backend}
+			var own []string
+			for _, r := range rs {
+				if r.Backend == backend && r.Table == table && len(r.Samples) > 0 {
+					s.Values = append(s.Values, millis(r.Samples)...)
+					if !slices.Contains(own, category(r)) {
+						own = append(own, category(r))
+					}
+				}
+			}
+			series = append(series, s)
+			labels = append(labels, strings.Join(own, "; "))
+		}
+
+		// A category shared by every series goes in the title
+		label, ok := shared(labels)
+		if !ok {
+			label = table
+			for i := range series {
+				if labels[i] != "" {
+					series[i].Label += " (" + labels[i] + ")"
+				}
+			}
+		}
+
+		chart := Chart{Title: title + " latency histogram, " + label, XLabel: "Latency (ms)"}
+		if err := Histogram(name+"_histogram_"+table, chart, series, 20); err != nil {
+			return err
+		}
+		chart = Chart{Title: title + " latency CDF, " + label, XLabel: "Latency (ms)"}
+		if err := CDF(name+"_cdf_"+table, chart, series, 0.5, 0.95, 0.99); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shared returns the label of the series with results when they all have
+// the same one
+func shared(labels []string) (string, bool) {
+	var first string
+	for _, l := range labels {
+		if l == "" {
+			continue
+		}
+		if first == "" {
+			first = l
+		} else if l != first {
+			return "", false
+		}
+	}
+	return first, first != ""
+}
+
+// millis converts latencies from microseconds to milliseconds
+func millis(samples []float64) []float64 {
+	ms := make([]float64, len(samples))
+	for i, s := range samples {
+		ms[i] = s / 1000
+	}
+	return ms
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// cdfPoints returns the empirical CDF of sorted samples as a step function
+func cdfPoints(sorted []float64) plotter.XYs {
+	if len(sorted) == 0 {
+		return nil
+	}
+
+	n := float64(len(sorted))
+	points := plotter.XYs{{X: sorted[0], Y: 0}}
+	for i, v := range sorted {
+		points = append(points, plotter.XY{X: v, Y: float64(i) / n}, plotter.XY{X: v, Y: float64(i+1) / n})
+	}
+	return points
+}
@@ -0,0 +1,76 @@
+package plot
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"benchmarkDB/results"
+)
+
+// svg draws the charts in memory and returns them by name
+func svg(t *testing.T, draw func() error) map[string][]byte {
+	t.Helper()
+	charts, err := CaptureSVG(draw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string][]byte)
+	for _, c := range charts {
+		byName[c.Name] = c.SVG
+	}
+	return byName
+}
+
+func TestBoxLegendsEverySeriesWithBoxes(t *testing.T) {
+	series := []Series{
+		{Label: "Empty", Samples: [][]float64{nil, nil}},
+		{Label: "MongoDB", Samples: [][]float64{{1, 2, 3}, nil}},
+		{Label: "MySQL", Samples: [][]float64{nil, {4, 5, 6}}},
+	}
+	chart := svg(t, func() error { return Box("box", Chart{}, []string{"a", "b"}, series) })["box"]
+	for _, label := range []string{"MongoDB", "MySQL"} {
+		if !bytes.Contains(chart, []byte(label)) {
+			t.Errorf("no legend for %s", label)
+		}
+	}
+	if bytes.Contains(chart, []byte("Empty")) {
+		t.Error("legend for a series without boxes")
+	}
+
+	if err := Box("box", Chart{}, []string{"a"}, []Series{{Label: "Empty"}}); err != ErrNoData {
+		t.Errorf("no boxes: %v, want ErrNoData", err)
+	}
+}
+
+func TestDistributionsLabelEachSeries(t *testing.T) {
+	category := func(r results.Result) string {
+		if r.Workers > 1 {
+			return "table1, 4 workers"
+		}
+		return "table1"
+	}
+	rs := []results.Result{
+		{Backend: "MongoDB", Table: "table1", Workers: 1, Samples: []float64{1000, 2000, 3000}},
+		{Backend: "MySQL", Table: "table1", Workers: 4, Samples: []float64{1500, 2500, 3500}},
+	}
+	charts := svg(t, func() error { return Distributions("d", "Read", rs, category) })
+	for _, name := range []string{"d_histogram_table1", "d_cdf_table1"} {
+		for _, label := range []string{"MongoDB (table1)", "MySQL (table1, 4 workers)"} {
+			if !bytes.Contains(charts[name], []byte(label)) {
+				t.Errorf("%s has no series labelled %q", name, label)
+			}
+		}
+	}
+
+	// A category shared by both series is in the title
+	rs[1].Workers = 1
+	charts = svg(t, func() error { return Distributions("d", "Read", rs, category) })
+	if !bytes.Contains(charts["d_cdf_table1"], []byte("Read latency CDF, table1")) || bytes.Contains(charts["d_cdf_table1"], []byte("MySQL (")) {
+		t.Error("shared category not in the title")
+	}
+
+	if err := Distributions("d", "Read", []results.Result{{Backend: "MySQL", Table: "table1"}}, category); !errors.Is(err, ErrNoData) {
+		t.Errorf("no samples: %v, want ErrNoData", err)
+	}
+}
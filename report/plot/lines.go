@@ -0,0 +1,58 @@
+package plot
+
+import (
+	"sort"
+
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+)
+
+// Line is one series of a line chart
+type Line struct {
+	Label   string
+	Points  plotter.XYs
+	Dashed  bool // e.g. to tell p99 from p50 of the same backend
+	Markers bool // mark each point, for lines through a few measurements
+}
+
+// Lines draws a line chart
+func Lines(name string, chart Chart, lines []Line) error {
+	p := newPlot(chart)
+
+	drawn := 0
+	for i, l := range lines {
+		if len(l.Points) == 0 {
+			continue
+		}
+		points := append(plotter.XYs(nil), l.Points...)
+		sort.SliceStable(points, func(a, b int) bool { return points[a].X < points[b].X })
+
+		line, err := plotter.NewLine(points)
+		if err != nil {
+			return err
+		}
+		line.Color = Color(l.Label, i)
+		if l.Dashed {
+			line.Dashes = plotutil.Dashes(1)
+		}
+		p.Add(line)
+
+		if l.Markers {
+			markers, err := plotter.NewScatter(points)
+			if err != nil {
+				return err
+			}
+			markers.Color = line.Color
+			p.Add(markers)
+			p.Legend.Add(l.Label, line, markers)
+		} else {
+			p.Legend.Add(l.Label, line)
+		}
+		drawn++
+	}
+	if drawn == 0 {
+		return ErrNoData
+	}
+
+	return save(p, name)
+}
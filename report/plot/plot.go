@@ -0,0 +1,225 @@
+// Package plot renders benchmark results as charts. Every chart uses the same
+// colour for a backend, labels its axes with units and is written to the
+// configured output directory.
+package plot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	gplot "gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+	"gonum.org/v1/plot/vg/vgpdf"
+	"gonum.org/v1/plot/vg/vgsvg"
+)
+
+// ErrNoData is returned when a chart has nothing to draw, e.g. results
+// without samples, and no chart was written
+var ErrNoData = errors.New("nothing to plot")
+
+// Config controls where and how charts are written. A chart named "read" is
+// saved as <Dir>/<Prefix>read.<Format>.
+type Config struct {
+	Dir    string
+	Prefix string
+	Format string  // png, svg or pdf
+	Width  float64 // inches
+	Height float64 // inches
+	DPI    int     // resolution of png charts
+}
+
+// Default is the configuration used by all charts
+var Default = Config{Dir: "./plots", Prefix: "plot_", Format: "png", Width: 10, Height: 6, DPI: 96}
+
+// Formats lists the supported chart file formats
+var Formats = []string{"png", "svg", "pdf"}
+
+// Path of the file a chart with the given name is written to
+func (c Config) Path(name string) string {
+	return filepath.Join(c.Dir, c.Prefix+name+"."+c.Format)
+}
+
+// Validate checks the format and size of the charts
+func (c Config) Validate() error {
+	if !slices.Contains(Formats, c.Format) {
+		return fmt.Errorf("unknown chart format %q (expected one of %s)", c.Format, strings.Join(Formats, ", "))
+	}
+	if c.Width <= 0 || c.Height <= 0 {
+		return errors.New("chart width and height must be positive")
+	}
+	if c.DPI <= 0 {
+		return errors.New("chart DPI must be positive")
+	}
+	return nil
+}
+
+// Chart holds the title and axis labels of a chart
+type Chart struct {
+	Title  string
+	XLabel string
+	YLabel string
+	LogX   bool   // log scale x axis
+	XTicks []Tick // fixed x axis ticks, e.g. worker counts or percentiles
+}
+
+// Tick is a labelled position on an axis
+type Tick struct {
+	Value float64
+	Label string
+}
+
+// Series is a named set of values, usually one per backend
+type Series struct {
+	Label   string
+	Values  []float64   // one value per category for bar charts, the samples for CDFs and histograms
+	Samples [][]float64 // samples per category for box plots
+}
+
+// backendColors keeps every backend the same colour across all charts
+var backendColors = map[string]color.Color{
+	"MongoDB": color.RGBA{R: 0x4d, G: 0xb3, B: 0x3d, A: 0xff},
+	"MySQL":   color.RGBA{R: 0x00, G: 0x75, B: 0x8f, A: 0xff},
+}
+
+// Color returns the colour of a series. Labels starting with a backend name
+// ("MySQL p99") get that backend's colour, others one from the default palette.
+func Color(label string, i int) color.Color {
+	for backend, c := range backendColors {
+		if strings.HasPrefix(label, backend) {
+			return c
+		}
+	}
+	return plotutil.Color(i + 2)
+}
+
+func newPlot(chart Chart) *gplot.Plot {
+	p := gplot.New()
+	p.Title.Text = chart.Title
+	p.X.Label.Text = chart.XLabel
+	p.Y.Label.Text = chart.YLabel
+	if chart.LogX {
+		p.X.Scale = gplot.LogScale{}
+	}
+	if len(chart.XTicks) > 0 {
+		ticks := make([]gplot.Tick, len(chart.XTicks))
+		for i, t := range chart.XTicks {
+			ticks[i] = gplot.Tick{Value: t.Value, Label: t.Label}
+		}
+		p.X.Tick.Marker = gplot.ConstantTicks(ticks)
+	}
+	p.Legend.Top = true
+	p.Legend.Left = true
+	return p
+}
+
+// nominalAxis spaces categories evenly along x and leaves room for the legend
+// above the tallest value
+func nominalAxis(p *gplot.Plot, categories []string) {
+	p.NominalX(categories...)
+	p.X.Min = -0.5
+	p.X.Max = float64(len(categories)) - 0.5
+	p.Y.Max *= 1.15
+	p.Legend.Left = false
+}
+
+// Rendered is a chart drawn while CaptureSVG or Record runs
+type Rendered struct {
+	Name string
+	Path string // file the chart was written to, empty for charts kept in memory
+	SVG  []byte // the chart, for charts kept in memory
+}
+
+// capture collects the charts while CaptureSVG or Record runs
+type capture struct {
+	inMemory bool
+	charts   []Rendered
+}
+
+var captured *capture
+
+// CaptureSVG renders the charts drawn by draw as SVG in memory instead of
+// writing them to files, in the order they were drawn. It must not be used
+// concurrently with other charts.
+func CaptureSVG(draw func() error) ([]Rendered, error) {
+	return record(draw, true)
+}
+
+// Record writes the charts drawn by draw as usual and returns the files they
+// were written to, in the order they were drawn. It must not be used
+// concurrently with other charts.
+func Record(draw func() error) ([]Rendered, error) {
+	return record(draw, false)
+}
+
+func record(draw func() error, inMemory bool) ([]Rendered, error) {
+	captured = &capture{inMemory: inMemory}
+	defer func() { captured = nil }()
+
+	err := draw()
+	return captured.charts, err
+}
+
+// save writes the chart to the file for name
+func save(p *gplot.Plot, name string) error {
+	if captured != nil && captured.inMemory {
+		c := Default
+		c.Format = "svg"
+		var buf bytes.Buffer
+		if _, err := Render(p, c).WriteTo(&buf); err != nil {
+			return err
+		}
+		captured.charts = append(captured.charts, Rendered{Name: name, SVG: buf.Bytes()})
+		return nil
+	}
+
+	path := Default.Path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := Render(p, Default).WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if captured != nil {
+		captured.charts = append(captured.charts, Rendered{Name: name, Path: path})
+	}
+	return nil
+}
+
+// Render draws the chart on a canvas of the configured format and size
+func Render(p *gplot.Plot, c Config) io.WriterTo {
+	w, h := vg.Length(c.Width)*vg.Inch, vg.Length(c.Height)*vg.Inch
+
+	var canvas interface {
+		vg.CanvasSizer
+		io.WriterTo
+	}
+	switch c.Format {
+	case "svg":
+		canvas = vgsvg.New(w, h)
+	case "pdf":
+		canvas = vgpdf.New(w, h)
+	default:
+		canvas = vgimg.PngCanvas{Canvas: vgimg.NewWith(vgimg.UseWH(w, h), vgimg.UseDPI(c.DPI))}
+	}
+	p.Draw(draw.New(canvas))
+	return canvas
+}
@@ -0,0 +1,313 @@
+// Package report turns a saved benchmark run into documents: a self-contained
+// HTML page and a Markdown summary.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"benchmarkDB/dataset"
+	"benchmarkDB/results"
+	"benchmarkDB/stats"
+)
+
+// Comparison of the backends on one operation, table, worker count and pool
+// size
+type Comparison struct {
+	Operation string
+	Table     string
+	Workers   int
+	PoolSize  int
+	Results   []results.Result // one per backend, in the order they ran
+	Metric    string           // what the winner was picked on, lower is better
+	Winner    string           // backend with the lowest value, "" with fewer than two backends
+	RunnerUp  string           // backend with the next lowest value
+	Margin    float64          // how much higher the runner-up is, in percent
+	Test      stats.Test       // significance of the difference between winner and runner-up
+	Tested    bool             // false when the results are single measurements
+}
+
+// Compare groups the results of a run by operation, table, worker count and
+// pool size and picks the faster backend of each group
+func Compare(run *results.Run) []Comparison {
+	var comparisons []Comparison
+	index := make(map[string]int)
+	for _, r := range run.Results {
+		key := fmt.Sprintf("%s/%s/%d/%d", r.Operation, r.Table, r.Workers, r.PoolSize)
+		i, ok := index[key]
+		if !ok {
+			i = len(comparisons)
+			index[key] = i
+			comparisons = append(comparisons, Comparison{Operation: r.Operation, Table: r.Table, Workers: r.Workers, PoolSize: r.PoolSize})
+		}
+		comparisons[i].Results = append(comparisons[i].Results, r)
+	}
+
+	for i := range comparisons {
+		comparisons[i].pickWinner()
+	}
+	return comparisons
+}
+
+// pickWinner compares p50 latency when every backend has one and the total
+// time taken otherwise. Backends that failed or took no time are left out.
+func (c *Comparison) pickWinner() {
+	c.Metric = "p50 latency"
+	value := func(r results.Result) float64 { return r.Latency.P50 }
+	for _, r := range c.Results {
+		if r.Latency.P50 == 0 {
+			c.Metric = "time"
+			value = func(r results.Result) float64 { return r.DurationSeconds }
+			break
+		}
+	}
+
+	var measured []results.Result
+	for _, r := range c.Results {
+		if value(r) > 0 {
+			measured = append(measured, r)
+		}
+	}
+	if len(measured) < 2 {
+		return
+	}
+	sort.SliceStable(measured, func(a, b int) bool { return value(measured[a]) < value(measured[b]) })
+	c.Winner = measured[0].Backend
+	c.RunnerUp = measured[1].Backend
+	c.Margin = (value(measured[1]) - value(measured[0])) / value(measured[0]) * 100
+	c.Test, c.Tested = Significance(measured[0], measured[1])
+}
+
+// Significant reports whether the winner is faster beyond the noise
+func (c Comparison) Significant() bool {
+	return c.Tested && c.Test.Significant()
+}
+
+// Summary describes the outcome in a sentence, e.g.
+// "MySQL wins, MongoDB takes 23% longer (p50 latency, Mann-Whitney U p=0.002)"
+// or "no significant difference between MySQL and MongoDB (...)"
+func (c Comparison) Summary() string {
+	switch {
+	case c.Winner == "":
+		return "not enough results to compare"
+	case c.Tested && !c.Test.Significant():
+		return fmt.Sprintf("no significant difference between %s and %s (%s p=%.3f)", c.Winner, c.RunnerUp, c.Test.Name, c.Test.P)
+	case c.Tested:
+		return fmt.Sprintf("%s wins, %s takes %.0f%% longer (%s, %s p=%.3f)", c.Winner, c.RunnerUp, c.Margin, c.Metric, c.Test.Name, c.Test.P)
+	case c.Margin < 1:
+		return fmt.Sprintf("%s and %s are within 1%% (%s, single measurement)", c.Winner, c.RunnerUp, c.Metric)
+	default:
+		return fmt.Sprintf("%s wins, %s takes %.0f%% longer (%s, single measurement, not tested for significance)", c.Winner, c.RunnerUp, c.Margin, c.Metric)
+	}
+}
+
+// ConfidenceInterval of the latency of a result in microseconds: a bootstrap
+// interval of the median for results with per-iteration samples, and the
+// interval of the mean from its standard deviation otherwise
+func ConfidenceInterval(r results.Result) (stats.Interval, bool) {
+	if len(r.Samples) >= 2 {
+		return stats.Bootstrap(r.Samples), true
+	}
+	if r.Operations >= 2 && r.Latency.StdDev > 0 {
+		return stats.MeanInterval(r.Latency.Mean, r.Latency.StdDev, r.Operations), true
+	}
+	return stats.Interval{}, false
+}
+
+// Label names the group, e.g. "read on table1 (1k rows), 4 workers"
+func (c Comparison) Label() string {
+	return c.Operation + " on " + c.Where()
+}
+
+// Where names the table, worker count and pool size of the group, e.g.
+// "table1 (1k rows), 4 workers, pool of 2"
+func (c Comparison) Where() string {
+	where := dataset.Label(c.Table)
+	if c.Table == "all" {
+		where = "all tables"
+	}
+	if c.Workers > 1 {
+		where += fmt.Sprintf(", %d workers", c.Workers)
+	}
+	if c.PoolSize > 0 {
+		where += fmt.Sprintf(", pool of %d", c.PoolSize)
+	}
+	return where
+}
+
+// Failures returns the results that recorded errors or timeouts
+func Failures(run *results.Run) []results.Result {
+	var failed []results.Result
+	for _, r := range run.Results {
+		if r.Errors > 0 || r.Timeouts > 0 {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// Writes returns the results that counted the rows their writes matched,
+// modified or upserted
+func Writes(run *results.Run) []results.Result {
+	var writes []results.Result
+	for _, r := range run.Results {
+		if r.Wrote() {
+			writes = append(writes, r)
+		}
+	}
+	return writes
+}
+
+// Operations lists the operations of a run in the order they ran
+func Operations(run *results.Run) []string {
+	var operations []string
+	for _, r := range run.Results {
+		if !slices.Contains(operations, r.Operation) {
+			operations = append(operations, r.Operation)
+		}
+	}
+	return operations
+}
+
+// Field is a named value of the run configuration or environment
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Fields lists the fields of v that are set, by their JSON names
+func Fields(v interface{}) []Field {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil
+	}
+
+	fields := make([]Field, 0, len(values))
+	for name, value := range values {
+		fields = append(fields, Field{Name: name, Value: format(value)})
+	}
+	sort.Slice(fields, func(a, b int) bool { return fields[a].Name < fields[b].Name })
+	return fields
+}
+
+// EnvironmentFields lists the fields of the environment with the servers
+// and drivers one per field, e.g. "MySQL server: 8.0.36, InnoDB, 128 MiB cache"
+func EnvironmentFields(env results.Environment) []Field {
+	servers, drivers := env.Servers, env.Drivers
+	env.Servers, env.Drivers = nil, nil
+	fields := Fields(env)
+	for i, f := range fields {
+		if f.Name == "memory_bytes" {
+			fields[i] = Field{Name: "memory", Value: results.FormatBytes(env.MemoryBytes)}
+		}
+	}
+	for _, s := range servers {
+		fields = append(fields, Field{Name: s.Backend + " server", Value: s.String()})
+	}
+	paths := make([]string, 0, len(drivers))
+	for path := range drivers {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fields = append(fields, Field{Name: path, Value: drivers[path]})
+	}
+	return fields
+}
+
+func format(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprint(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// filter returns the results of one operation
+func filter(rs []results.Result, operation string) []results.Result {
+	var filtered []results.Result
+	for _, r := range rs {
+		if r.Operation == operation {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// WriteSummary prints the outcome of every comparison of the run with the
+// confidence interval of each backend's latency
+func WriteSummary(w io.Writer, run *results.Run) {
+	fmt.Fprintln(w, "************Comparison of backends***************")
+	for _, c := range Compare(run) {
+		fmt.Fprintf(w, "%s: %s\n", c.Label(), c.Summary())
+		var intervals []string
+		for _, r := range c.Results {
+			if ci, ok := ConfidenceInterval(r); ok {
+				intervals = append(intervals, fmt.Sprintf("%s %.3f ms [%.3f, %.3f]", r.Backend, ci.Estimate/1000, ci.Low/1000, ci.High/1000))
+			}
+		}
+		if len(intervals) > 0 {
+			fmt.Fprintf(w, "    %.0f%% confidence intervals: %s\n", stats.Confidence*100, strings.Join(intervals, ", "))
+		}
+		for _, r := range c.Results {
+			if len(r.Server) > 0 {
+				fmt.Fprintf(w, "    %s server: %s\n", r.Backend, r.Server)
+			}
+			if r.Plan != nil {
+				fmt.Fprintf(w, "    %s plan: %s\n", r.Backend, r.Plan)
+			}
+			if r.Pool != nil {
+				fmt.Fprintf(w, "    %s connections: %s\n", r.Backend, r.Pool)
+			}
+		}
+	}
+	fmt.Fprintln(w, "*************************************************")
+	WriteCounts(w, run)
+	WriteErrors(w, run)
+}
+
+// WriteCounts prints how many rows or documents the writes of each backend,
+// table and operation matched, modified and upserted
+func WriteCounts(w io.Writer, run *results.Run) {
+	writes := Writes(run)
+	if len(writes) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "************Rows written***************")
+	for _, r := range writes {
+		fmt.Fprintf(w, "%s %s %s: %d matched, %d modified, %d upserted\n", r.Backend, r.Table, r.Operation, r.Matched, r.Modified, r.Upserted)
+	}
+	fmt.Fprintln(w, "***************************************")
+}
+
+// WriteErrors prints how many operations of each backend, table and operation
+// succeeded, failed and timed out, followed by the recorded errors
+func WriteErrors(w io.Writer, run *results.Run) {
+	failures := Failures(run)
+	if len(failures) == 0 && len(run.Errors) == 0 && run.Stopped == "" {
+		return
+	}
+	fmt.Fprintln(w, "************Errors***************")
+	if run.Stopped != "" {
+		fmt.Fprintf(w, "Run stopped early (%s), the results are partial\n", run.Stopped)
+	}
+	for _, r := range failures {
+		fmt.Fprintf(w, "%s %s %s: %d succeeded, %d failed, %d timed out\n", r.Backend, r.Table, r.Operation, r.Successes(), r.Errors, r.Timeouts)
+	}
+	for _, e := range run.Errors {
+		fmt.Fprintln(w, "    "+e.String())
+	}
+	fmt.Fprintln(w, "*********************************")
+}
@@ -0,0 +1,93 @@
+package results
+
+import (
+	"errors"
+	"fmt"
+
+	"benchmarkDB/runctx"
+)
+
+// Error is a failure recorded during a run. Operations failing with the same
+// message on the same backend and table are counted in one Error.
+type Error struct {
+	Backend   string `json:"backend"`
+	Table     string `json:"table"`
+	Operation string `json:"operation"`
+	Message   string `json:"message"`
+	Count     int64  `json:"count"`
+}
+
+func (e Error) String() string {
+	s := e.Operation
+	if e.Table != "" {
+		s = e.Table + " " + s
+	}
+	if e.Backend != "" {
+		s = e.Backend + " " + s
+	}
+	s += ": " + e.Message
+	if e.Count > 1 {
+		s += fmt.Sprintf(" (%d times)", e.Count)
+	}
+	return s
+}
+
+// AddError records a failed operation. It is safe to call from several goroutines.
+func (r *Run) AddError(backend, table, operation string, err error) {
+	r.AddErrors(backend, table, operation, err.Error(), 1)
+}
+
+// AddErrors records count failed operations with the same message
+func (r *Run) AddErrors(backend, table, operation, message string, count int64) {
+	if count <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addErrors(Error{Backend: backend, Table: table, Operation: operation, Message: message, Count: count})
+}
+
+func (r *Run) addErrors(e Error) {
+	for i, existing := range r.Errors {
+		if existing.Backend == e.Backend && existing.Table == e.Table && existing.Operation == e.Operation && existing.Message == e.Message {
+			r.Errors[i].Count += e.Count
+			return
+		}
+	}
+	r.Errors = append(r.Errors, e)
+}
+
+// Add appends a result together with the errors of its failed operations,
+// which are counted in its Operations and in its Timeouts or Errors. The
+// per-row metrics are filled in from its Rows.
+func (r *Run) Add(result Result, errs ...error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, err := range errs {
+		r.addErrors(Error{Backend: result.Backend, Table: result.Table, Operation: result.Operation, Message: err.Error(), Count: 1})
+		if errors.Is(err, runctx.ErrTimeout) {
+			result.Timeouts++
+		} else {
+			result.Errors++
+		}
+	}
+	result.Operations += int64(len(errs))
+	result.Normalize()
+	r.Results = append(r.Results, result)
+}
+
+// Failed reports whether any operation of the run failed or timed out, or the
+// run was stopped early
+func (r *Run) Failed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.Errors) > 0 || r.Stopped != "" {
+		return true
+	}
+	for _, result := range r.Results {
+		if result.Errors > 0 || result.Timeouts > 0 {
+			return true
+		}
+	}
+	return false
+}
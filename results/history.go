@@ -0,0 +1,103 @@
+package results
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HistoryFile keeps every saved run, one JSON document per line, so runs can
+// be compared after their plots and result files have been overwritten
+const HistoryFile = "history.jsonl"
+
+// appendHistory adds the run to the history file
+func appendHistory(r *Run) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(Dir, HistoryFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// History returns every run recorded in the history file, oldest first
+func History() ([]*Run, error) {
+	f, err := os.Open(filepath.Join(Dir, HistoryFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var runs []*Run
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 256*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", HistoryFile, line, err)
+		}
+		runs = append(runs, &run)
+	}
+	return runs, scanner.Err()
+}
+
+// Find returns a run by the path of its result file, its ID or a prefix of
+// its ID that matches a single run in the history
+func Find(ref string) (*Run, error) {
+	if info, err := os.Stat(ref); err == nil && !info.IsDir() {
+		return Load(ref)
+	}
+
+	runs, err := History()
+	if err != nil {
+		return nil, err
+	}
+	var matches []*Run
+	for _, run := range runs {
+		if run.ID == ref {
+			return run, nil
+		}
+		if strings.HasPrefix(run.ID, ref) {
+			matches = append(matches, run)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no run %q in %s", ref, filepath.Join(Dir, HistoryFile))
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%q matches %d runs, use more of the run ID", ref, len(matches))
+	}
+}
+
+// Latest returns the most recent run of a benchmark in the history, leaving
+// out the run with the given ID
+func Latest(benchmark, except string) (*Run, error) {
+	runs, err := History()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].Benchmark == benchmark && runs[i].ID != except {
+			return runs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no %s run in %s", benchmark, filepath.Join(Dir, HistoryFile))
+}
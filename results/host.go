@@ -0,0 +1,94 @@
+package results
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// driverModules are the client drivers whose versions are recorded
+var driverModules = []string{"github.com/go-sql-driver/mysql", "go.mongodb.org/mongo-driver"}
+
+// kernel is the release of the running Linux kernel, "" elsewhere
+func kernel() string {
+	data, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// cpuModel is the model name of the first CPU in /proc/cpuinfo, "" when
+// there is none
+func cpuModel() string {
+	value, _ := procField("/proc/cpuinfo", "model name")
+	return value
+}
+
+// memoryBytes is the total memory in /proc/meminfo, 0 when there is none
+func memoryBytes() int64 {
+	value, ok := procField("/proc/meminfo", "MemTotal")
+	if !ok {
+		return 0
+	}
+	kb, err := strconv.ParseInt(strings.TrimSuffix(value, " kB"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return kb * 1024
+}
+
+// procField is the value of the first "name: value" line of a /proc file
+func procField(path, name string) (string, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.TrimSpace(key) == name {
+			return strings.TrimSpace(value), true
+		}
+	}
+	return "", false
+}
+
+// drivers are the versions of the client drivers the binary was built with,
+// as required by go.mod
+func drivers() map[string]string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	versions := make(map[string]string)
+	for _, dep := range info.Deps {
+		for _, path := range driverModules {
+			if dep.Path == path {
+				versions[path] = dep.Version
+				if dep.Replace != nil {
+					versions[path] = dep.Replace.Version
+				}
+			}
+		}
+	}
+	return versions
+}
+
+// FormatBytes formats a size in bytes with a binary unit, e.g. "128 MiB"
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.4g %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
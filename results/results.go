@@ -0,0 +1,415 @@
+// Package results holds the structured outcome of a benchmark run and reads
+// and writes it as JSON under the results directory.
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"benchmarkDB/histogram"
+	"benchmarkDB/stats"
+)
+
+// Dir is where result files are written
+const Dir = "./results"
+
+// Run is everything recorded about one invocation of a benchmark
+type Run struct {
+	ID          string      `json:"id"`
+	Benchmark   string      `json:"benchmark"`
+	Started     time.Time   `json:"started"`
+	Finished    time.Time   `json:"finished"`
+	Config      Config      `json:"config"`
+	Environment Environment `json:"environment"`
+	Results     []Result    `json:"results"`
+	Series      []Series    `json:"series,omitempty"`
+	Errors      []Error     `json:"errors,omitempty"`
+	Stopped     string      `json:"stopped,omitempty"` // why the run ended early, e.g. "interrupted"
+
+	mu sync.Mutex // guards Results and Errors while operations record failures
+}
+
+// Config records the options the run was started with
+type Config struct {
+	Workload       string  `json:"workload,omitempty"`
+	OperationCount int     `json:"operation_count,omitempty"`
+	Duration       string  `json:"duration,omitempty"`
+	TargetRate     float64 `json:"target_rate,omitempty"`
+	Workers        int     `json:"workers,omitempty"`
+	HitRatio       float64 `json:"hit_ratio,omitempty"` // share of upserts on existing keys
+	SampleInterval string  `json:"sample_interval,omitempty"`
+	Sweep          []int   `json:"sweep,omitempty"`      // worker counts of a concurrency sweep
+	PoolSizes      []int   `json:"pool_sizes,omitempty"` // pool sizes of a pool sweep
+	TLS            bool    `json:"tls,omitempty"`        // clients connected over TLS
+}
+
+// Environment describes the machine the benchmark ran on, the client
+// drivers and the database servers
+type Environment struct {
+	GoVersion   string            `json:"go_version"`
+	OS          string            `json:"os"`
+	Kernel      string            `json:"kernel,omitempty"`
+	Arch        string            `json:"arch"`
+	Hostname    string            `json:"hostname,omitempty"`
+	CPUModel    string            `json:"cpu_model,omitempty"`
+	CPUs        int               `json:"cpus"`
+	GOMAXPROCS  int               `json:"gomaxprocs"`
+	MemoryBytes int64             `json:"memory_bytes,omitempty"`
+	Drivers     map[string]string `json:"drivers,omitempty"` // module path to version, from go.mod
+	Servers     []Server          `json:"servers,omitempty"`
+}
+
+// Server describes the database server one backend ran against
+type Server struct {
+	Backend       string `json:"backend"`
+	Version       string `json:"version,omitempty"`
+	StorageEngine string `json:"storage_engine,omitempty"`
+	CacheBytes    int64  `json:"cache_bytes,omitempty"` // InnoDB buffer pool or WiredTiger cache size
+	Error         string `json:"error,omitempty"`       // why the server could not be described
+}
+
+// String summarizes the server, e.g. "8.0.36, InnoDB, 128 MiB cache"
+func (s Server) String() string {
+	if s.Error != "" {
+		return "unknown (" + s.Error + ")"
+	}
+	parts := []string{s.Version}
+	if s.StorageEngine != "" {
+		parts = append(parts, s.StorageEngine)
+	}
+	if s.CacheBytes > 0 {
+		parts = append(parts, FormatBytes(s.CacheBytes)+" cache")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// CurrentEnvironment describes the machine this process runs on. The servers
+// are described by the benchmarks once they connected.
+func CurrentEnvironment() Environment {
+	hostname, _ := os.Hostname()
+	return Environment{
+		GoVersion:   runtime.Version(),
+		OS:          runtime.GOOS,
+		Kernel:      kernel(),
+		Arch:        runtime.GOARCH,
+		Hostname:    hostname,
+		CPUModel:    cpuModel(),
+		CPUs:        runtime.NumCPU(),
+		GOMAXPROCS:  runtime.GOMAXPROCS(0),
+		MemoryBytes: memoryBytes(),
+		Drivers:     drivers(),
+	}
+}
+
+// Result of one operation type against one table on one backend
+type Result struct {
+	Backend         string    `json:"backend"`
+	Table           string    `json:"table"`
+	Rows            int       `json:"rows,omitempty"` // rows in the table at the start of the run
+	Operation       string    `json:"operation"`
+	Workers         int       `json:"workers"`
+	PoolSize        int       `json:"pool_size,omitempty"` // connections each client was limited to, in a pool sweep
+	Operations      int64     `json:"operations"`          // attempted, including failed ones
+	Errors          int64     `json:"errors"`              // failed operations, other than timeouts
+	Timeouts        int64     `json:"timeouts,omitempty"`  // operations that ran out of time
+	Matched         int64     `json:"matched,omitempty"`   // rows or documents the writes selected
+	Modified        int64     `json:"modified,omitempty"`  // rows or documents the writes changed
+	Upserted        int64     `json:"upserted,omitempty"`  // rows or documents the writes inserted
+	DurationSeconds float64   `json:"duration_seconds"`
+	Throughput      float64   `json:"throughput"`
+	Latency         Latency   `json:"latency_us"`
+	LatencyPerRow   float64   `json:"latency_per_row_us,omitempty"` // mean latency divided by Rows
+	RowsPerSecond   float64   `json:"rows_per_second,omitempty"`    // throughput multiplied by Rows
+	Samples         []float64 `json:"samples_us,omitempty"`         // per-iteration latencies, when kept
+	Server          Metrics   `json:"server,omitempty"`             // server status while the operation ran, when collected
+	Plan            *Plan     `json:"plan,omitempty"`               // how the server runs the query, when explained
+	Pool            *Pool     `json:"pool,omitempty"`               // connection checkouts while the operation ran, when counted
+}
+
+// Pool of client connections while an operation ran. MySQL counts the
+// checkouts that waited for a connection to be freed, MongoDB times every
+// checkout, including opening a connection when none is idle.
+type Pool struct {
+	Size           int     `json:"size"`                       // most connections the pool opens, 0 for no limit
+	Checkouts      int64   `json:"checkouts,omitempty"`        // connections taken from the pool, MongoDB only
+	Waits          int64   `json:"waits,omitempty"`            // checkouts that waited for a connection, MySQL only
+	WaitSeconds    float64 `json:"wait_seconds"`               // total time spent getting connections
+	MaxWaitSeconds float64 `json:"max_wait_seconds,omitempty"` // longest checkout, MongoDB only
+	Open           int     `json:"open"`                       // connections open at the end
+}
+
+// String summarizes the pool, e.g. "pool of 8, 120 waits, 35ms waiting, 8 open"
+func (p Pool) String() string {
+	size := "unlimited pool"
+	if p.Size > 0 {
+		size = fmt.Sprintf("pool of %d", p.Size)
+	}
+	wait := time.Duration(p.WaitSeconds * float64(time.Second)).Round(time.Microsecond)
+	if p.Checkouts > 0 {
+		longest := time.Duration(p.MaxWaitSeconds * float64(time.Second)).Round(time.Microsecond)
+		return fmt.Sprintf("%s, %d checkouts, %v waiting (longest %v), %d open", size, p.Checkouts, wait, longest, p.Open)
+	}
+	return fmt.Sprintf("%s, %d waits, %v waiting, %d open", size, p.Waits, wait, p.Open)
+}
+
+// Plan of a query as the server explained it
+type Plan struct {
+	Query        string          `json:"query,omitempty"`         // the statement or command explained
+	Access       string          `json:"access,omitempty"`        // MySQL access type, e.g. ALL or ref, or MongoDB stage, e.g. COLLSCAN or IXSCAN
+	Index        string          `json:"index,omitempty"`         // index used, none for a full scan
+	Examined     int64           `json:"examined"`                // rows or documents examined
+	KeysExamined int64           `json:"keys_examined,omitempty"` // index keys examined, MongoDB only
+	Returned     int64           `json:"returned"`                // rows or documents returned
+	Estimated    bool            `json:"estimated,omitempty"`     // the counts are the optimizer's estimates, as MySQL's are
+	Raw          json.RawMessage `json:"raw,omitempty"`           // the full plan as the server returned it
+	Error        string          `json:"error,omitempty"`         // why the query could not be explained
+}
+
+// String summarizes the plan, e.g. "COLLSCAN, no index, 1000 examined, 12 returned"
+func (p Plan) String() string {
+	if p.Error != "" {
+		return "unknown (" + p.Error + ")"
+	}
+	access := p.Access
+	if access == "" {
+		access = "no table access"
+	}
+	index := "no index"
+	if p.Index != "" {
+		index = "index " + p.Index
+	}
+	approx := ""
+	if p.Estimated {
+		approx = "~"
+	}
+	s := fmt.Sprintf("%s, %s, %s%d examined", access, index, approx, p.Examined)
+	if p.KeysExamined > 0 {
+		s += fmt.Sprintf(" (%d keys)", p.KeysExamined)
+	}
+	return s + fmt.Sprintf(", %s%d returned", approx, p.Returned)
+}
+
+// Metrics of a server while an operation ran: the change of each status
+// counter and, named "<gauge> max", the highest value of each gauge
+type Metrics map[string]float64
+
+// String lists the metrics sorted by name, e.g.
+// "Innodb_rows_read +1000, Questions +21, Threads_running max 2"
+func (m Metrics) String() string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		if strings.HasSuffix(name, " max") {
+			parts[i] = fmt.Sprintf("%s %.0f", name, m[name])
+		} else {
+			parts[i] = fmt.Sprintf("%s %+.0f", name, m[name])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Successes counts the operations that neither failed nor timed out
+func (r Result) Successes() int64 {
+	return r.Operations - r.Errors - r.Timeouts
+}
+
+// Wrote reports whether the result counted the rows its writes matched,
+// modified or upserted
+func (r Result) Wrote() bool {
+	return r.Matched > 0 || r.Modified > 0 || r.Upserted > 0
+}
+
+// Normalize fills in the per-row metrics from Rows
+func (r *Result) Normalize() {
+	if r.Rows <= 0 {
+		return
+	}
+	r.LatencyPerRow = r.Latency.Mean / float64(r.Rows)
+	r.RowsPerSecond = r.Throughput * float64(r.Rows)
+}
+
+// Latency summary in microseconds
+type Latency struct {
+	Min    float64 `json:"min"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+	P50    float64 `json:"p50"`
+	P90    float64 `json:"p90"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+	P999   float64 `json:"p999"`
+	Max    float64 `json:"max"`
+}
+
+// Micros is a latency of the results, in microseconds, as a duration
+func Micros(us float64) time.Duration {
+	return time.Duration(us * float64(time.Microsecond))
+}
+
+// Series is the throughput and latency of one backend on one table sampled
+// at a fixed interval during the run
+type Series struct {
+	Backend         string   `json:"backend"`
+	Table           string   `json:"table"`
+	Workers         int      `json:"workers"`
+	IntervalSeconds float64  `json:"interval_seconds"`
+	Samples         []Sample `json:"samples"`
+}
+
+// Sample covers the operations completed in one interval, latencies in microseconds
+type Sample struct {
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Operations     int64   `json:"operations"`
+	Errors         int64   `json:"errors"`
+	Throughput     float64 `json:"throughput"`
+	P50            float64 `json:"p50_us"`
+	P99            float64 `json:"p99_us"`
+}
+
+// Timing is a result measured as the total time taken by a batch of operations
+func Timing(backend, table, operation string, d time.Duration, operations int) Result {
+	r := Result{
+		Backend:         backend,
+		Table:           table,
+		Operation:       operation,
+		Workers:         1,
+		Operations:      int64(operations),
+		DurationSeconds: d.Seconds(),
+	}
+	if d > 0 {
+		r.Throughput = float64(operations) / d.Seconds()
+	}
+	return r
+}
+
+// Iterations is a result measured as the latency of each of a number of
+// repeated operations. samples are in seconds.
+func Iterations(backend, table, operation string, samples []float64) Result {
+	var total float64
+	micros := make([]float64, len(samples))
+	for i, s := range samples {
+		total += s
+		micros[i] = s * 1e6
+	}
+
+	r := Result{
+		Backend:         backend,
+		Table:           table,
+		Operation:       operation,
+		Workers:         1,
+		Operations:      int64(len(samples)),
+		DurationSeconds: total,
+		Latency:         SummarizeSamples(micros),
+		Samples:         micros,
+	}
+	if total > 0 {
+		r.Throughput = float64(len(samples)) / total
+	}
+	return r
+}
+
+// SummarizeSamples computes the latency summary of individual samples in microseconds
+func SummarizeSamples(samples []float64) Latency {
+	if len(samples) == 0 {
+		return Latency{}
+	}
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, s := range sorted {
+		sum += s
+	}
+	mean := sum / float64(len(sorted))
+	var squares float64
+	for _, s := range sorted {
+		squares += (s - mean) * (s - mean)
+	}
+
+	return Latency{
+		Min:    sorted[0],
+		Mean:   mean,
+		StdDev: math.Sqrt(squares / float64(len(sorted))),
+		P50:    stats.Quantile(sorted, 0.50),
+		P90:    stats.Quantile(sorted, 0.90),
+		P95:    stats.Quantile(sorted, 0.95),
+		P99:    stats.Quantile(sorted, 0.99),
+		P999:   stats.Quantile(sorted, 0.999),
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// Summarize reduces a latency histogram recorded in microseconds
+func Summarize(h *histogram.Histogram) Latency {
+	return Latency{
+		Min:    float64(h.Min()),
+		Mean:   h.Mean(),
+		StdDev: h.StdDev(),
+		P50:    float64(h.ValueAtQuantile(0.50)),
+		P90:    float64(h.ValueAtQuantile(0.90)),
+		P95:    float64(h.ValueAtQuantile(0.95)),
+		P99:    float64(h.ValueAtQuantile(0.99)),
+		P999:   float64(h.ValueAtQuantile(0.999)),
+		Max:    float64(h.Max()),
+	}
+}
+
+// NewRun starts a run of the named benchmark, e.g. "ycsb-a". Its ID is the
+// benchmark and the start time to the microsecond, so runs started within the
+// same second do not overwrite each other's files.
+func NewRun(benchmark string, config Config) *Run {
+	started := time.Now()
+	return &Run{
+		ID:          fmt.Sprintf("%s-%s-%06d", benchmark, started.Format("20060102-150405"), started.Nanosecond()/1000),
+		Benchmark:   benchmark,
+		Started:     started,
+		Config:      config,
+		Environment: CurrentEnvironment(),
+	}
+}
+
+// Path of a file belonging to the run, e.g. Path(".hlog")
+func (r *Run) Path(ext string) string {
+	return filepath.Join(Dir, r.ID+ext)
+}
+
+// Save writes the run to Dir/<id>.json, adds it to the history and returns the path
+func (r *Run) Save() (string, error) {
+	if err := os.MkdirAll(Dir, 0o755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := r.Path(".json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return path, appendHistory(r)
+}
+
+// Load reads a run saved with Save
+func Load(path string) (*Run, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &run, nil
+}
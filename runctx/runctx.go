@@ -0,0 +1,124 @@
+// Package runctx bounds a benchmark run: a deadline for the whole run, a
+// timeout for each database operation and a clean stop on SIGINT.
+package runctx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Config of the limits, set from the command line
+type Config struct {
+	Deadline  time.Duration // longest the whole run may take, 0 for no limit
+	OpTimeout time.Duration // longest a single database operation may take, 0 for no limit
+}
+
+// Default limits used by every benchmark
+var Default = Config{OpTimeout: 30 * time.Second}
+
+// Validate checks that the limits are not negative
+func (c Config) Validate() error {
+	if c.Deadline < 0 || c.OpTimeout < 0 {
+		return errors.New("deadline and operation timeout must not be negative")
+	}
+	return nil
+}
+
+// ErrTimeout is wrapped by the errors of operations that ran out of time
+var ErrTimeout = errors.New("operation timed out")
+
+// ErrInterrupted is the cause of a run stopped by SIGINT or SIGTERM
+var ErrInterrupted = errors.New("interrupted")
+
+// Start returns the context of a run. It is cancelled when the deadline
+// passes or the process receives SIGINT or SIGTERM, after which operations
+// stop being issued so the partial results can be written. A second signal
+// kills the process. stop releases the context.
+func Start() (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancelDeadline := func() {}
+	if Default.Deadline > 0 {
+		ctx, cancelDeadline = context.WithTimeout(ctx, Default.Deadline)
+	}
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			fmt.Println("\nInterrupted, stopping and saving partial results (interrupt again to quit)")
+			cancel(ErrInterrupted)
+		case <-done:
+			return
+		}
+		select {
+		case <-signals:
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancelDeadline()
+		cancel(nil)
+	}
+}
+
+// Operation derives the context of a single database operation
+func Operation(ctx context.Context) (context.Context, context.CancelFunc) {
+	if Default.OpTimeout > 0 {
+		return context.WithTimeout(ctx, Default.OpTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// Do runs a database operation under the operation timeout. The error of an
+// operation that ran out of time wraps ErrTimeout. When the run stopped
+// while the operation was running the run's error is returned instead, see
+// Stopped.
+func Do(ctx context.Context, op func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	opCtx, cancel := Operation(ctx)
+	defer cancel()
+
+	err := op(opCtx)
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case errors.Is(opCtx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%w after %v: %v", ErrTimeout, Default.OpTimeout, err)
+	}
+	return err
+}
+
+// Stopped reports whether err means the run was stopped rather than that the
+// operation failed. Operations cut short this way are not counted.
+func Stopped(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// Reason describes why the run stopped early, "" when it was not stopped
+func Reason(ctx context.Context) string {
+	switch {
+	case ctx.Err() == nil:
+		return ""
+	case errors.Is(context.Cause(ctx), ErrInterrupted):
+		return "interrupted"
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Sprintf("deadline of %v exceeded", Default.Deadline)
+	default:
+		return ctx.Err().Error()
+	}
+}
@@ -0,0 +1,271 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"benchmarkDB/results"
+	"benchmarkDB/runctx"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Metrics turns on polling the status of the servers while each operation
+// is measured, set from the command line
+var Metrics = false
+
+// Interval between two polls of a server's status while an operation runs
+var Interval = time.Second
+
+// gauges are the metrics that hold a current value rather than count up.
+// They are reported as the highest value seen, the others as the change.
+var gauges = map[string]bool{
+	"Threads_running":        true,
+	"connections.current":    true,
+	"wiredTiger.cache.bytes": true,
+}
+
+// pollers are the clients the status is read over, connected apart from the
+// benchmarked clients so that polling neither takes connections from the
+// pools being measured nor waits for them
+var pollers struct {
+	mongo *mongo.Client
+	mysql *sql.DB
+}
+
+// PollOver sets the clients Watch reads the status of each server over. Until
+// then, or for a nil client, the status is read over the watched client.
+func PollOver(mongoClient *mongo.Client, mysqlDB *sql.DB) {
+	pollers.mongo, pollers.mysql = mongoClient, mysqlDB
+}
+
+// poller is the client the status of the server of client is read over
+func poller(client interface{}) interface{} {
+	switch client.(type) {
+	case *mongo.Client:
+		if pollers.mongo != nil {
+			return pollers.mongo
+		}
+	case *sql.DB:
+		if pollers.mysql != nil {
+			return pollers.mysql
+		}
+	}
+	return client
+}
+
+// ownQueries counts the status queries sent to each kind of server, by the
+// counter the server also counts them in: every SHOW GLOBAL STATUS is one of
+// MySQL's Questions and every serverStatus one of MongoDB's commands
+var ownQueries = map[string]*atomic.Int64{
+	"Questions":          new(atomic.Int64),
+	"opcounters.command": new(atomic.Int64),
+}
+
+// queryCounter is the counter the status queries of client are counted in
+func queryCounter(client interface{}) string {
+	switch client.(type) {
+	case *mongo.Client:
+		return "opcounters.command"
+	case *sql.DB:
+		return "Questions"
+	default:
+		return ""
+	}
+}
+
+// Watcher polls the status of one server while an operation runs
+type Watcher struct {
+	client  interface{}
+	before  map[string]float64
+	queries int64 // status queries sent up to the first snapshot
+	peaks   map[string]float64
+	mu      sync.Mutex // guards peaks
+	failed  func(error)
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Report returns a function recording the errors of collecting the metrics
+// of an operation on a backend's table in the run
+func Report(run *results.Run, backend, table string) func(error) {
+	return func(err error) {
+		run.AddError(backend, table, "server metrics", err)
+	}
+}
+
+// Watch takes the status of the server of client and keeps polling it until
+// Stop. failed is called with the errors of the status queries, possibly from
+// another goroutine, except those of a stopped run. It returns nil when
+// Metrics is off or the server cannot be queried, Stop on a nil Watcher
+// returns nil.
+func Watch(ctx context.Context, client interface{}, failed func(error)) *Watcher {
+	if !Metrics {
+		return nil
+	}
+	client = poller(client)
+	before, err := snapshot(ctx, client)
+	if err != nil {
+		if !runctx.Stopped(err) {
+			failed(err)
+		}
+		return nil
+	}
+	w := &Watcher{client: client, before: before, peaks: make(map[string]float64), failed: failed, stop: make(chan struct{}), done: make(chan struct{})}
+	if counter := queryCounter(client); counter != "" {
+		w.queries = ownQueries[counter].Load()
+	}
+	w.observe(before)
+	go w.poll(ctx)
+	return w
+}
+
+func (w *Watcher) poll(ctx context.Context) {
+	defer close(w.done)
+	ticker := time.NewTicker(Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			status, err := snapshot(ctx, w.client)
+			if err != nil {
+				if !runctx.Stopped(err) {
+					w.failed(err)
+				}
+				continue
+			}
+			w.observe(status)
+		}
+	}
+}
+
+// observe keeps the highest value of each gauge
+func (w *Watcher) observe(status map[string]float64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for name, value := range status {
+		if gauges[name] && value > w.peaks[name] {
+			w.peaks[name] = value
+		}
+	}
+}
+
+// Stop ends polling and returns the change of each counter since Watch and,
+// named "<gauge> max", the highest value of each gauge. The status queries
+// of every Watcher in between are taken out of the counter they are counted
+// in. It returns nil when the server could not be queried at the end.
+func (w *Watcher) Stop() results.Metrics {
+	if w == nil {
+		return nil
+	}
+	close(w.stop)
+	<-w.done
+
+	// The run may have stopped, the final status is still wanted
+	after, err := snapshot(context.Background(), w.client)
+	if err != nil {
+		w.failed(err)
+		return nil
+	}
+	w.observe(after)
+
+	metrics := make(results.Metrics)
+	for name, value := range after {
+		if gauges[name] {
+			metrics[name+" max"] = w.peaks[name]
+		} else if before, ok := w.before[name]; ok {
+			metrics[name] = value - before
+		}
+	}
+	if counter := queryCounter(w.client); counter != "" {
+		if _, ok := metrics[counter]; ok {
+			metrics[counter] -= float64(ownQueries[counter].Load() - w.queries)
+		}
+	}
+	return metrics
+}
+
+// snapshot reads the status counters and gauges of a server
+func snapshot(ctx context.Context, client interface{}) (map[string]float64, error) {
+	status := make(map[string]float64)
+	if counter := queryCounter(client); counter != "" {
+		ownQueries[counter].Add(1)
+	}
+	err := runctx.Do(ctx, func(ctx context.Context) error {
+		switch c := client.(type) {
+		case *mongo.Client:
+			return mongoStatus(ctx, c, status)
+		case *sql.DB:
+			return mysqlStatus(ctx, c, status)
+		default:
+			return errors.New("unsupported client type")
+		}
+	})
+	return status, err
+}
+
+func mongoStatus(ctx context.Context, mongoClient *mongo.Client, status map[string]float64) error {
+	var s struct {
+		Opcounters struct {
+			Insert  int64 `bson:"insert"`
+			Query   int64 `bson:"query"`
+			Update  int64 `bson:"update"`
+			Delete  int64 `bson:"delete"`
+			Getmore int64 `bson:"getmore"`
+			Command int64 `bson:"command"`
+		} `bson:"opcounters"`
+		Connections struct {
+			Current int64 `bson:"current"`
+		} `bson:"connections"`
+		WiredTiger struct {
+			Cache struct {
+				Bytes        int64 `bson:"bytes currently in the cache"`
+				PagesRead    int64 `bson:"pages read into cache"`
+				PagesWritten int64 `bson:"pages written from cache"`
+			} `bson:"cache"`
+		} `bson:"wiredTiger"`
+	}
+	if err := mongoClient.Database("admin").RunCommand(ctx, bson.D{{Key: "serverStatus", Value: 1}}).Decode(&s); err != nil {
+		return err
+	}
+	status["opcounters.insert"] = float64(s.Opcounters.Insert)
+	status["opcounters.query"] = float64(s.Opcounters.Query)
+	status["opcounters.update"] = float64(s.Opcounters.Update)
+	status["opcounters.delete"] = float64(s.Opcounters.Delete)
+	status["opcounters.getmore"] = float64(s.Opcounters.Getmore)
+	status["opcounters.command"] = float64(s.Opcounters.Command)
+	status["connections.current"] = float64(s.Connections.Current)
+	status["wiredTiger.cache.bytes"] = float64(s.WiredTiger.Cache.Bytes)
+	status["wiredTiger.cache.pages_read"] = float64(s.WiredTiger.Cache.PagesRead)
+	status["wiredTiger.cache.pages_written"] = float64(s.WiredTiger.Cache.PagesWritten)
+	return nil
+}
+
+func mysqlStatus(ctx context.Context, mysqlDB *sql.DB, status map[string]float64) error {
+	query := "SHOW GLOBAL STATUS WHERE Variable_name LIKE 'Innodb_rows_%' OR Variable_name IN ('Threads_running', 'Questions')"
+	rows, err := mysqlDB.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return err
+		}
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			status[name] = v
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,72 @@
+// Package server queries the database servers a benchmark runs against for
+// what describes them: version, storage engine and cache size.
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"benchmarkDB/results"
+	"benchmarkDB/runctx"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DescribeAll describes both servers. A server that cannot be described is
+// recorded with the error instead of failing the run.
+func DescribeAll(ctx context.Context, mongoClient *mongo.Client, mysqlDB *sql.DB) []results.Server {
+	return []results.Server{Describe(ctx, "MongoDB", mongoClient), Describe(ctx, "MySQL", mysqlDB)}
+}
+
+// Describe queries the server of a client
+func Describe(ctx context.Context, backend string, client interface{}) results.Server {
+	s := results.Server{Backend: backend}
+	err := runctx.Do(ctx, func(ctx context.Context) error {
+		switch c := client.(type) {
+		case *mongo.Client:
+			return describeMongo(ctx, c, &s)
+		case *sql.DB:
+			return describeMySQL(ctx, c, &s)
+		default:
+			return errors.New("unsupported client type")
+		}
+	})
+	if err != nil {
+		s.Error = err.Error()
+	}
+	return s
+}
+
+func describeMongo(ctx context.Context, mongoClient *mongo.Client, s *results.Server) error {
+	admin := mongoClient.Database("admin")
+	var build struct {
+		Version string `bson:"version"`
+	}
+	if err := admin.RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&build); err != nil {
+		return err
+	}
+	var status struct {
+		StorageEngine struct {
+			Name string `bson:"name"`
+		} `bson:"storageEngine"`
+		WiredTiger struct {
+			Cache struct {
+				MaxBytes int64 `bson:"maximum bytes configured"`
+			} `bson:"cache"`
+		} `bson:"wiredTiger"`
+	}
+	if err := admin.RunCommand(ctx, bson.D{{Key: "serverStatus", Value: 1}}).Decode(&status); err != nil {
+		return err
+	}
+	s.Version = build.Version
+	s.StorageEngine = status.StorageEngine.Name
+	s.CacheBytes = status.WiredTiger.Cache.MaxBytes
+	return nil
+}
+
+func describeMySQL(ctx context.Context, mysqlDB *sql.DB, s *results.Server) error {
+	query := "SELECT VERSION(), @@default_storage_engine, @@innodb_buffer_pool_size"
+	return mysqlDB.QueryRowContext(ctx, query).Scan(&s.Version, &s.StorageEngine, &s.CacheBytes)
+}
@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Confidence level of the intervals
+const Confidence = 0.95
+
+// Resamples drawn by Bootstrap
+const Resamples = 2000
+
+// Interval is a confidence interval around an estimate
+type Interval struct {
+	Estimate  float64
+	Low, High float64
+}
+
+// Overlaps reports whether the two intervals share any values
+func (i Interval) Overlaps(other Interval) bool {
+	return i.Low <= other.High && other.Low <= i.High
+}
+
+// Bootstrap estimates a Confidence interval of the median of the samples with
+// the percentile bootstrap. The resampling is seeded so the same samples give
+// the same interval in every report.
+func Bootstrap(samples []float64) Interval {
+	if len(samples) == 0 {
+		return Interval{}
+	}
+	r := rand.New(rand.NewSource(1))
+	medians := make([]float64, Resamples)
+	resample := make([]float64, len(samples))
+	for i := range medians {
+		for j := range resample {
+			resample[j] = samples[r.Intn(len(samples))]
+		}
+		sort.Float64s(resample)
+		medians[i] = Quantile(resample, 0.5)
+	}
+	sort.Float64s(medians)
+
+	tail := (1 - Confidence) / 2
+	return Interval{
+		Estimate: Median(samples),
+		Low:      Quantile(medians, tail),
+		High:     Quantile(medians, 1-tail),
+	}
+}
+
+// MeanInterval is the Confidence interval of a mean known only by its
+// standard deviation and sample size, from the normal approximation
+func MeanInterval(mean, sd float64, n int64) Interval {
+	if n < 2 {
+		return Interval{Estimate: mean, Low: mean, High: mean}
+	}
+	// Two-sided critical value of the normal distribution for Confidence
+	z := math.Sqrt2 * math.Erfinv(Confidence)
+	half := z * sd / math.Sqrt(float64(n))
+	return Interval{Estimate: mean, Low: mean - half, High: mean + half}
+}
+
+// Median of the samples
+func Median(samples []float64) float64 {
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	return Quantile(sorted, 0.5)
+}
+
+// Quantile of sorted values, interpolating between the closest ranks
+func Quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
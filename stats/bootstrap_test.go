@@ -0,0 +1,60 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestBootstrap(t *testing.T) {
+	if got := Bootstrap(nil); got != (Interval{}) {
+		t.Errorf("no samples: %+v", got)
+	}
+	if got := Bootstrap([]float64{4, 4, 4}); got != (Interval{Estimate: 4, Low: 4, High: 4}) {
+		t.Errorf("constant samples: %+v", got)
+	}
+
+	// The median of n standard normal samples has a standard error of about
+	// sqrt(pi/2n), so the 95% interval is about 0.16 wide for 1000 samples
+	r := rand.New(rand.NewSource(7))
+	samples := make([]float64, 1000)
+	for i := range samples {
+		samples[i] = r.NormFloat64()
+	}
+	got := Bootstrap(samples)
+	if got.Estimate != Median(samples) || got.Low > got.Estimate || got.High < got.Estimate {
+		t.Errorf("interval %+v does not hold the median %v", got, Median(samples))
+	}
+	if width := got.High - got.Low; width < 0.11 || width > 0.21 {
+		t.Errorf("interval %+v is %.3f wide, want about 0.16", got, width)
+	}
+	if again := Bootstrap(samples); again != got {
+		t.Errorf("resampling is not repeatable: %+v then %+v", got, again)
+	}
+}
+
+func TestMeanInterval(t *testing.T) {
+	got := MeanInterval(10, 2, 16)
+	// 1.959963984540054 is the 97.5th percentile of the normal distribution
+	if half := 1.959963984540054 * 2 / 4; math.Abs(got.High-10-half) > 1e-12 || math.Abs(10-got.Low-half) > 1e-12 {
+		t.Errorf("MeanInterval(10, 2, 16) = %+v, want 10 ± %v", got, half)
+	}
+	if got := MeanInterval(10, 2, 1); got != (Interval{Estimate: 10, Low: 10, High: 10}) {
+		t.Errorf("single sample: %+v", got)
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4}
+	for _, tt := range []struct{ q, want float64 }{{0, 1}, {0.5, 2.5}, {1, 4}, {0.25, 1.75}} {
+		if got := Quantile(sorted, tt.q); got != tt.want {
+			t.Errorf("Quantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+	if got := Quantile(nil, 0.5); got != 0 {
+		t.Errorf("Quantile of nothing = %v", got)
+	}
+	if got := Median([]float64{3, 1, 2}); got != 2 {
+		t.Errorf("Median = %v, want 2", got)
+	}
+}
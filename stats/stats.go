@@ -0,0 +1,174 @@
+// Package stats holds the significance tests used to tell real changes in
+// latency from run-to-run noise.
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Alpha is the significance level below which a difference is reported as significant
+const Alpha = 0.05
+
+// Test is the outcome of a two-sided significance test
+type Test struct {
+	Name      string  // e.g. "Mann-Whitney U"
+	Statistic float64 // U or t
+	P         float64 // probability of a difference at least this large if both samples came from the same distribution
+}
+
+// Significant reports whether the difference is significant at level Alpha
+func (t Test) Significant() bool {
+	return t.P < Alpha
+}
+
+// MannWhitney compares two independent samples without assuming they are
+// normally distributed, which latencies rarely are. The p-value uses the
+// normal approximation with a correction for ties, good from about 8
+// samples on each side.
+func MannWhitney(a, b []float64) Test {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return Test{Name: "Mann-Whitney U", P: 1}
+	}
+
+	type value struct {
+		v     float64
+		fromA bool
+	}
+	values := make([]value, 0, len(a)+len(b))
+	for _, v := range a {
+		values = append(values, value{v, true})
+	}
+	for _, v := range b {
+		values = append(values, value{v, false})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].v < values[j].v })
+
+	// Rank sum of a, tied values sharing the mean of their ranks
+	var rankSumA, ties float64
+	for i := 0; i < len(values); {
+		j := i
+		for j < len(values) && values[j].v == values[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if values[k].fromA {
+				rankSumA += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	u := rankSumA - n1*(n1+1)/2
+	n := n1 + n2
+	mean := n1 * n2 / 2
+	variance := n1 * n2 / 12 * (n + 1 - ties/(n*(n-1)))
+	if variance <= 0 {
+		return Test{Name: "Mann-Whitney U", Statistic: u, P: 1}
+	}
+
+	// Continuity correction towards the mean
+	z := math.Max(math.Abs(u-mean)-0.5, 0) / math.Sqrt(variance)
+	return Test{Name: "Mann-Whitney U", Statistic: u, P: math.Erfc(z / math.Sqrt2)}
+}
+
+// Welch compares the means of two samples known only by their mean,
+// standard deviation and size, without assuming equal variances
+func Welch(meanA, sdA float64, nA int64, meanB, sdB float64, nB int64) Test {
+	if nA < 2 || nB < 2 {
+		return Test{Name: "Welch's t", P: 1}
+	}
+	va := sdA * sdA / float64(nA)
+	vb := sdB * sdB / float64(nB)
+	if va+vb == 0 {
+		p := 1.0
+		if meanA != meanB {
+			p = 0
+		}
+		return Test{Name: "Welch's t", P: p}
+	}
+
+	t := (meanA - meanB) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/float64(nA-1) + vb*vb/float64(nB-1))
+	return Test{Name: "Welch's t", Statistic: t, P: studentTwoSided(t, df)}
+}
+
+// studentTwoSided is P(|T| >= |t|) for Student's t distribution with df
+// degrees of freedom
+func studentTwoSided(t, df float64) float64 {
+	x := df / (df + t*t)
+	return regularizedIncompleteBeta(df/2, 0.5, x)
+}
+
+// regularizedIncompleteBeta computes I_x(a, b) with the continued fraction
+// from Numerical Recipes
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges quickly only on this side
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+	}
+	return front * betaContinuedFraction(a, b, x) / a
+}
+
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+	c := 1.0
+	d := 1 - (a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		// Even step
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		// Odd step
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
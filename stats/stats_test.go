@@ -0,0 +1,121 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+// The reference values are closed forms where there is one, otherwise
+// numerical integrations of the densities and, for Mann-Whitney, the normal
+// approximation with tie and continuity corrections, as R's
+// wilcox.test(exact = FALSE) computes it.
+
+func TestRegularizedIncompleteBeta(t *testing.T) {
+	tests := []struct {
+		a, b, x float64
+		want    float64
+	}{
+		{1, 1, 0.3, 0.3},                  // uniform
+		{3, 1, 0.5, 0.125},                // x^a
+		{1, 4, 0.2, 1 - math.Pow(0.8, 4)}, // 1 - (1-x)^b
+		{2.5, 2.5, 0.5, 0.5},              // symmetric
+		{0.5, 0.5, 0.25, 1.0 / 3},         // arcsine, 2/pi asin(sqrt(x))
+		{5, 0.5, 0.9, 0.3166429150200182}, // past (a+1)/(a+b+2), the fraction of the other side
+		{2, 3, 0, 0},
+		{2, 3, 1, 1},
+	}
+	for _, tt := range tests {
+		if got := regularizedIncompleteBeta(tt.a, tt.b, tt.x); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("I_%v(%v, %v) = %v, want %v", tt.x, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestStudentTwoSided(t *testing.T) {
+	tests := []struct {
+		t, df float64
+		want  float64
+	}{
+		{2.228138851986, 10, 0.05}, // qt(0.975, 10)
+		{2.570581835636, 5, 0.05},
+		{2.042272456301, 30, 0.05},
+		{-2.228138851986, 10, 0.05},
+		{1, 1, 0.5},                // Cauchy, 1 - 2/pi atan(t)
+		{2, 2, 1 - 2/math.Sqrt(6)}, // 1 - t/sqrt(2+t^2)
+		{3, 4, 0.03994196807171713},
+		{0, 7, 1},
+	}
+	for _, tt := range tests {
+		if got := studentTwoSided(tt.t, tt.df); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("studentTwoSided(%v, %v) = %v, want %v", tt.t, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestWelch(t *testing.T) {
+	tests := []struct {
+		meanA, sdA float64
+		nA         int64
+		meanB, sdB float64
+		nB         int64
+		wantT      float64
+		wantP      float64
+	}{
+		// Equal variances and sizes, 10 degrees of freedom
+		{0, 1, 6, 2.228138851986 * math.Sqrt(1.0/3), 1, 6, -2.228138851986, 0.05},
+		// Unequal variances, 11.97 degrees of freedom
+		{5, 1, 5, 3, 2, 9, 2.491364395612199, 0.028402650315971556},
+		{4, 1, 10, 4, 3, 10, 0, 1},
+	}
+	for _, tt := range tests {
+		got := Welch(tt.meanA, tt.sdA, tt.nA, tt.meanB, tt.sdB, tt.nB)
+		if math.Abs(got.Statistic-tt.wantT) > 1e-9 || math.Abs(got.P-tt.wantP) > 1e-9 {
+			t.Errorf("Welch(%v, %v, %d, %v, %v, %d) = t %v p %v, want t %v p %v",
+				tt.meanA, tt.sdA, tt.nA, tt.meanB, tt.sdB, tt.nB, got.Statistic, got.P, tt.wantT, tt.wantP)
+		}
+	}
+
+	// Without a spread the means are either equal or certainly different
+	if p := Welch(1, 0, 5, 1, 0, 5).P; p != 1 {
+		t.Errorf("equal constant samples: p %v", p)
+	}
+	if p := Welch(1, 0, 5, 2, 0, 5).P; p != 0 {
+		t.Errorf("different constant samples: p %v", p)
+	}
+	if p := Welch(1, 1, 1, 2, 1, 5).P; p != 1 {
+		t.Errorf("single sample: p %v", p)
+	}
+}
+
+func TestMannWhitney(t *testing.T) {
+	tests := []struct {
+		a, b  []float64
+		wantU float64
+		wantP float64
+	}{
+		// Completely separated
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8}, []float64{9, 10, 11, 12, 13, 14, 15, 16}, 0, 0.0009391056991172597},
+		// Ties within and across the samples
+		{[]float64{1, 2, 2, 3, 4, 5, 5, 6}, []float64{3, 4, 5, 5, 6, 7, 7, 8, 9}, 12.5, 0.025496068631499558},
+		// Interleaved
+		{[]float64{1.1, 2.3, 3.5, 4.2, 5.0, 6.1, 7.7, 8.4}, []float64{1.5, 2.1, 3.9, 4.4, 5.5, 6.0, 7.2, 8.8}, 31, 0.9581219265777428},
+	}
+	for _, tt := range tests {
+		got := MannWhitney(tt.a, tt.b)
+		if got.Statistic != tt.wantU || math.Abs(got.P-tt.wantP) > 1e-9 {
+			t.Errorf("MannWhitney(%v, %v) = U %v p %v, want U %v p %v", tt.a, tt.b, got.Statistic, got.P, tt.wantU, tt.wantP)
+		}
+		// The test is symmetric
+		swapped := MannWhitney(tt.b, tt.a)
+		if math.Abs(swapped.P-got.P) > 1e-12 {
+			t.Errorf("swapping the samples changed p from %v to %v", got.P, swapped.P)
+		}
+	}
+
+	if p := MannWhitney(nil, []float64{1, 2}).P; p != 1 {
+		t.Errorf("empty sample: p %v", p)
+	}
+	if p := MannWhitney([]float64{3, 3, 3}, []float64{3, 3}).P; p != 1 {
+		t.Errorf("all values tied: p %v", p)
+	}
+}
@@ -1,10 +1,13 @@
 package ui
 
 import (
+	"benchmarkDB/connect"
 	"benchmarkDB/create"
 	"benchmarkDB/delete"
 	"benchmarkDB/read"
 	"benchmarkDB/update"
+	"benchmarkDB/upsert"
+	"benchmarkDB/ycsb"
 	"fmt"
 	"strings"
 )
@@ -22,6 +25,12 @@ func runProgram(option string) {
 		update.Update()
 	case "delete":
 		delete.Delete()
+	case "upsert":
+		upsert.Upsert()
+	case "connect":
+		connect.Connect()
+	case "workloada", "workloadb", "workloadc", "workloadd", "workloade", "workloadf":
+		ycsb.RunWorkload(option, ycsb.DefaultOptions())
 	default:
 		fmt.Printf("No program found for option: %s\n", option)
 	}
@@ -15,7 +15,7 @@ type model struct {
 
 func InitialModel() model {
 	return model{
-		choices:  []string{"Create", "Read", "Update", "Delete"},
+		choices:  []string{"Create", "Read", "Update", "Delete", "Upsert", "Connect", "Workload A", "Workload B", "Workload C", "Workload D", "Workload E", "Workload F"},
 		selected: make(map[int]struct{}),
 	}
 }
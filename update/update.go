@@ -5,224 +5,645 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"os"
+	"sync"
 	"time"
 
+	"benchmarkDB/bench"
+	"benchmarkDB/create"
+	"benchmarkDB/dataset"
+	"benchmarkDB/explain"
+	"benchmarkDB/fixture"
+	"benchmarkDB/report/plot"
+	"benchmarkDB/results"
+	"benchmarkDB/runctx"
+	"benchmarkDB/server"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"gonum.org/v1/plot"
-	"gonum.org/v1/plot/plotter"
-	"gonum.org/v1/plot/vg"
 )
 
+// Options of the update benchmark
+type Options struct {
+	Workers int // concurrent clients of the read-modify-write phase
+}
+
+// Default options, set from the command line
+var Default = Options{Workers: 4}
+
+// Validate checks that the read-modify-write phase has a client to run on
+func (o Options) Validate() error {
+	if o.Workers < 1 {
+		return errors.New("workers must be at least 1")
+	}
+	return nil
+}
+
+// kind of update, i.e. how the rows are selected and changed
+type kind int
+
+const (
+	byKey     kind = iota // $set on one row by Name and Year
+	byFilter              // UpdateMany vs UPDATE ... WHERE Department = ?
+	increment             // $inc vs SET Earnings = Earnings + ?
+	upsert                // update or insert by Name and Year
+)
+
+// variant of the single-threaded update
+type variant struct {
+	kind      kind
+	operation string // recorded in the results
+	label     string // bar label in the charts
+}
+
+var variants = []variant{
+	{byKey, "update by key", "by key"},
+	{byFilter, "update by filter", fmt.Sprintf("by filter (%d rows)", filterRows)},
+	{increment, "increment", "increment"},
+	{upsert, "upsert", "upsert (half new keys)"},
+}
+
+// Rows matching the filter of one update by filter
+const filterRows = 10
+
+// Rows contended by the read-modify-write workers
+const hotRows = 5
+
+// Read-modify-writes done by each worker
+const rmwPerWorker = 25
+
+// writes counts the rows or documents updates matched, modified and upserted,
+// as reported by the backend
+type writes struct {
+	matched, modified, upserted int64
+}
+
+func (w *writes) add(other writes) {
+	w.matched += other.matched
+	w.modified += other.modified
+	w.upserted += other.upserted
+}
+
+func (w writes) record(r *results.Result) {
+	r.Matched, r.Modified, r.Upserted = w.matched, w.modified, w.upserted
+}
+
+func (w writes) String() string {
+	return fmt.Sprintf("%d matched, %d modified, %d upserted", w.matched, w.modified, w.upserted)
+}
+
+// rows an update variant runs against, created before the timed phase
+type rows struct {
+	keys    []create.Record // one row per iteration, updated by key and incremented
+	filters []create.Record // one row per iteration, standing for its Department of filterRows rows
+	upserts []create.Record // one key per iteration, the even ones already stored
+	hot     []create.Record // rows shared by the read-modify-write workers
+}
+
 func Update() {
+	opts := Default
 	tables := []string{"table1", "table2", "table3", "table4"} // Representing MySQL tables
-	record := "Chang Lee"
-	prevVal := "2019"
-	newVal := "9999"
-	field := "Year"
-	var mongoClient *mongo.Client
-	var mysqlDB *sql.DB
-	var err error // Declare err outside of the mongoClient initialization
-
-	// Initialize MongoDB client
-	mongoClient, err = initMongoClient()
-	if err != nil {
-		fmt.Println("Error initializing MongoDB client:", err)
-		return
-	}
-	defer mongoClient.Disconnect(context.Background())
-
-	// Initialize MySQL client
-	mysqlDB, err = initMySQLClient()
-	if err != nil {
-		fmt.Println("Error initializing MySQL client:", err)
-		return
-	}
-	defer mysqlDB.Close()
-
-	// Single Threaded
-	fmt.Println("************Performing single-threaded updates***************")
-	var mongoTimes []float64
-	var mysqlTimes []float64
 
+	// Connected to both backends, starting from the seed data
+	ctx, b := bench.Start(tables)
+	mongoClient, mysqlDB := b.Mongo, b.MySQL
+
+	backends := []struct {
+		name   string
+		client interface{}
+	}{{"MongoDB", mongoClient}, {"MySQL", mysqlDB}}
+
+	// Fixture phase, not timed: the rows every update below changes
+	fmt.Println("************Creating records to update***************")
+	start := time.Now()
+	fixtures := make(map[string]rows)
 	for _, table := range tables {
-		start := time.Now()
-		err := singleThreadedUpdate(mongoClient, table, field, record, prevVal, newVal)
-		if err != nil {
-			fmt.Printf("Error updating %s in MongoDB: %v\n", table, err)
-		} else {
-			mongoTimes = append(mongoTimes, time.Since(start).Seconds())
-			fmt.Println("Time taken for single-threaded MongoDB update in", table+":", time.Since(start))
+		f := newRows(table)
+		fixtures[table] = f
+		for _, backend := range backends {
+			// Without the reset, rows left by a run that did not finish
+			// would be matched too
+			var err error
+			if !fixture.Enabled {
+				err = deleteRows(ctx, backend.client, table)
+			}
+			if err == nil {
+				err = fixture.Insert(ctx, backend.client, table, append(expand(f.filters), append(f.keys, f.hot...)...))
+			}
+			if err == nil {
+				err = fixture.CreateKeyed(ctx, backend.client, upsertTable(table))
+			}
+			if err == nil {
+				err = fixture.Insert(ctx, backend.client, upsertTable(table), stored(f.upserts))
+			}
+			if err != nil {
+				bench.Fail(fmt.Sprintf("Error creating records to update in %s %s: %v", backend.name, table, err))
+			}
 		}
+	}
+	fmt.Printf("Created the records in %v (not part of the results)\n", time.Since(start).Round(time.Millisecond))
 
-		start = time.Now()
-		err = singleThreadedUpdate(mysqlDB, table, field, record, prevVal, newVal)
-		if err != nil {
-			fmt.Printf("Error updating %s in MySQL: %v\n", table, err)
-		} else {
-			mysqlTimes = append(mysqlTimes, time.Since(start).Seconds())
-			fmt.Println("    Time taken for single-threaded MySQL update in", table+":", time.Since(start))
+	// Single Threaded, every variant against every table
+	fmt.Println("************Performing single-threaded updates***************")
+	run := results.NewRun("update", results.Config{Workers: opts.Workers})
+	run.Environment.Servers = b.Servers()
+
+	for _, v := range variants {
+		for _, table := range tables {
+			if ctx.Err() != nil {
+				break
+			}
+			for _, backend := range backends {
+				// Untimed, explaining an update does not run it
+				plan := explain.Run(ctx, backend.client, updateQuery(table, v.kind, fixtures[table]))
+				watch := server.Watch(ctx, backend.client, server.Report(run, backend.name, table))
+				latencies, counts, errs := timedUpdates(ctx, backend.client, table, v.kind, fixtures[table])
+				bench.PrintErrors(fmt.Sprintf("running %s on %s in %s", v.operation, table, backend.name), errs)
+				r := results.Iterations(backend.name, table, v.operation, latencies)
+				r.Rows, _ = dataset.Rows(table)
+				counts.record(&r)
+				r.Server = watch.Stop()
+				r.Plan = plan
+				run.Add(r, errs...)
+				if len(latencies) > 0 {
+					fmt.Printf("    Time taken for %s %s in %s: %v (%v)\n", backend.name, v.operation, table, results.Micros(r.Latency.Mean), counts)
+				}
+			}
 		}
 	}
 	fmt.Println("*************************************************************")
 
-	// Multi Threaded
-	fmt.Println("************Performing multi-threaded updates***************")
+	// Multi Threaded, workers reading a row and writing it back changed
+	fmt.Printf("************Performing read-modify-writes (%d workers)***************\n", opts.Workers)
 	for _, table := range tables {
-		start := time.Now()
-		err := multiThreadedUpdate(mongoClient, table, field, record, prevVal, newVal)
-		if err != nil {
-			fmt.Printf("Error updating %s in multi-threaded MongoDB: %v\n", table, err)
-		} else {
-			fmt.Println("    Time taken for multi-threaded MongoDB update in", table+":", time.Since(start))
+		if ctx.Err() != nil {
+			break
 		}
+		for _, backend := range backends {
+			name := backend.name
+			var mu sync.Mutex
+			var errs []error
+			failed := func(err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				errs = append(errs, err)
+			}
+			var pending sync.WaitGroup
+			plan := explain.Run(ctx, backend.client, readQuery(table, fixtures[table].hot[0]))
+			watch := server.Watch(ctx, backend.client, server.Report(run, backend.name, table))
+			start := time.Now()
+			done := readModifyWrites(ctx, backend.client, table, fixtures[table].hot, opts.Workers, &pending, failed)
+			pending.Wait()
+			elapsed := time.Since(start)
+			bench.PrintErrors("running read-modify-writes on "+table+" in "+name, errs)
 
-		start = time.Now()
-		err = multiThreadedUpdate(mysqlDB, table, field, record, prevVal, newVal)
-		if err != nil {
-			fmt.Printf("Error updating %s in multi-threaded MySQL: %v\n", table, err)
-		} else {
-			fmt.Println("    Time taken for multi-threaded MySQL update in", table+":", time.Since(start))
+			r := results.Iterations(name, table, "read-modify-write", done.latencies)
+			r.Workers = opts.Workers
+			r.Rows, _ = dataset.Rows(table)
+			r.DurationSeconds = elapsed.Seconds()
+			r.Throughput = float64(len(done.latencies)) / elapsed.Seconds()
+			done.counts.record(&r)
+			r.Server = watch.Stop()
+			r.Plan = plan
+			run.Add(r, errs...)
+			fmt.Printf("    Time taken for %s read-modify-writes in %s: %v (%v)\n", name, table, elapsed, done.counts)
+
+			// Concurrent read-modify-writes of the same row overwrite each other
+			if lost, err := lostUpdates(ctx, backend.client, table, fixtures[table].hot, int64(len(done.latencies))); err == nil && lost > 0 {
+				fmt.Printf("    %d of %d increments lost to concurrent read-modify-writes\n", lost, len(done.latencies))
+			}
 		}
 	}
 	fmt.Println("*************************************************************")
 
 	// Plotting
-	err = plotTimeBarChart("Time taken for Updates", tables, mongoTimes, mysqlTimes)
-	if err != nil {
-		fmt.Println("Error plotting update times:", err)
-	}
+	run.Finished = time.Now()
+	plotGraph(run, tables)
+	b.After(func() error {
+		var errs []error
+		for _, table := range tables {
+			for _, backend := range backends {
+				if err := fixture.Drop(context.Background(), backend.client, upsertTable(table)); err != nil {
+					errs = append(errs, fmt.Errorf("dropping %s %s: %v", backend.name, upsertTable(table), err))
+				}
+				// Without the reset the tables are not restored, the rows
+				// created to be updated are deleted instead
+				if fixture.Enabled {
+					continue
+				}
+				if err := deleteRows(context.Background(), backend.client, table); err != nil {
+					errs = append(errs, fmt.Errorf("deleting the records updated in %s %s: %v", backend.name, table, err))
+				}
+			}
+		}
+		return errors.Join(errs...)
+	})
+	b.Finish(run)
+}
 
-	if err != nil { // Check if there's any error occurred during the updates
-		fmt.Println("Program completed with errors")
-		os.Exit(1) // Exit with non-zero exit code to indicate failure
-	}
+// Number of times each single-threaded update is repeated
+const iterations = 20
 
-	fmt.Println("Program completed successfully")
-	os.Exit(0)
+// upsertTable is the table with a unique key on Name and Year the upserts of
+// a table run against, apart from the one of the upsert benchmark
+func upsertTable(table string) string {
+	return table + "_update_upsert"
 }
 
-func initMongoClient() (*mongo.Client, error) {
-	// Initialize MongoDB client
-	clientOptions := options.Client().ApplyURI("mongodb://localhost:27017")
-	client, err := mongo.Connect(context.Background(), clientOptions)
-	if err != nil {
-		return nil, err
-	}
+// School of the rows created to be updated, which no row of the dataset has
+const fixtureSchool = "Update benchmark"
 
-	// Check the connection
-	err = client.Ping(context.Background(), nil)
-	if err != nil {
-		return nil, err
+// newRows generates the rows updated in a table. Their Name and Year are not
+// in the dataset, so each update matches exactly the rows meant for it.
+func newRows(table string) rows {
+	row := func(name, department string) create.Record {
+		return create.Record{Name: name, School: fixtureSchool, Job: "Fixture", Department: department, Earnings: 50000, Year: 1900}
 	}
+	var f rows
+	for i := 0; i < iterations; i++ {
+		f.keys = append(f.keys, row(fmt.Sprintf("Update fixture key-%d", i), "Update benchmark keys"))
+		f.filters = append(f.filters, row(fmt.Sprintf("Update fixture filter-%d", i), fmt.Sprintf("Update benchmark filter-%d", i)))
+		f.upserts = append(f.upserts, row(fmt.Sprintf("Update fixture upsert-%d", i), "Update benchmark upserts"))
+	}
+	for i := 0; i < hotRows; i++ {
+		f.hot = append(f.hot, row(fmt.Sprintf("Update fixture hot-%d", i), "Update benchmark hot rows"))
+	}
+	return f
+}
 
-	return client, nil
+// deleteRows deletes the rows newRows created in a table. It runs even after
+// the run was stopped, bounded only by the operation timeout.
+func deleteRows(ctx context.Context, client interface{}, table string) error {
+	return runctx.Do(ctx, func(ctx context.Context) error {
+		switch c := client.(type) {
+		case *mongo.Client:
+			_, err := c.Database("MONGODB_DATABASE").Collection(table).DeleteMany(ctx, bson.M{"School": fixtureSchool})
+			return err
+		case *sql.DB:
+			_, err := c.ExecContext(ctx, "DELETE FROM "+table+" WHERE School = ?", fixtureSchool)
+			return err
+		default:
+			return errors.New("unsupported client type")
+		}
+	})
 }
 
-func initMySQLClient() (*sql.DB, error) {
-	// Initialize MySQL client
-	db, err := sql.Open("mysql", "MYSQL_USERNAME:SQL_PASStcp(localhost:3306)/MYSQL_DATABASE")
-	if err != nil {
-		return nil, err
+// expand turns each filter row into the filterRows rows of its Department
+func expand(filters []create.Record) []create.Record {
+	var records []create.Record
+	for _, filter := range filters {
+		for j := 0; j < filterRows; j++ {
+			record := filter
+			record.Name = fmt.Sprintf("%s-%d", filter.Name, j)
+			records = append(records, record)
+		}
 	}
+	return records
+}
 
-	// Check the connection
-	err = db.Ping()
-	if err != nil {
-		return nil, err
+// stored returns the upsert keys that exist before the upserts run, every
+// other one, so half of the upserts update and half insert
+func stored(upserts []create.Record) []create.Record {
+	var records []create.Record
+	for i := 0; i < len(upserts); i += 2 {
+		records = append(records, upserts[i])
 	}
+	return records
+}
+
+// timedUpdates runs iterations updates of a kind, each on its own rows, and
+// returns the latency of each successful update in seconds, the rows they
+// matched, modified and upserted and the errors of the failed ones. It
+// returns early when the run is stopped.
+func timedUpdates(ctx context.Context, client interface{}, table string, k kind, f rows) ([]float64, writes, []error) {
+	latencies := make([]float64, 0, iterations)
+	var counts writes
+	var errs []error
+	for i := 0; i < iterations; i++ {
+		target := f.keys[i]
+		switch k {
+		case byFilter:
+			target = f.filters[i]
+		case upsert:
+			target = f.upserts[i]
+		}
+
+		// MySQL reports only the rows an update changed, the rows it
+		// selects are counted beforehand and not timed
+		var matched int64
+		if mysqlDB, ok := client.(*sql.DB); ok && k != upsert {
+			err := runctx.Do(ctx, func(ctx context.Context) error {
+				var err error
+				matched, err = countMatches(ctx, mysqlDB, table, k, target)
+				return err
+			})
+			if runctx.Stopped(err) {
+				break
+			}
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
 
-	return db, nil
+		var w writes
+		start := time.Now()
+		err := runctx.Do(ctx, func(ctx context.Context) error {
+			var err error
+			w, err = singleThreadedUpdate(ctx, client, table, k, target, i+1)
+			return err
+		})
+		elapsed := time.Since(start)
+		if runctx.Stopped(err) {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		w.matched += matched
+		counts.add(w)
+		latencies = append(latencies, elapsed.Seconds())
+	}
+	return latencies, counts, errs
 }
 
-func singleThreadedUpdate(client interface{}, table, field, record, prevVal, newVal string) error {
+// singleThreadedUpdate runs one update of a kind on the rows target stands
+// for. value makes every update write something new.
+func singleThreadedUpdate(ctx context.Context, client interface{}, table string, k kind, target create.Record, value int) (writes, error) {
 	switch c := client.(type) {
 	case *mongo.Client:
 		mongoClient := c
-		updateQuery := generateMongoDBQuery(table, "", field, record, prevVal, newVal)
-		_, err := mongoClient.Database("MONGODB_DATABASE").Collection(table).UpdateOne(context.TODO(), bson.M{"Name": record}, updateQuery)
-		return err
+		collection, filter, update := mongoUpdate(table, k, target, value)
+		var result *mongo.UpdateResult
+		var err error
+		switch k {
+		case byFilter:
+			result, err = mongoClient.Database("MONGODB_DATABASE").Collection(collection).UpdateMany(ctx, filter, update)
+		case upsert:
+			result, err = mongoClient.Database("MONGODB_DATABASE").Collection(collection).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		default:
+			result, err = mongoClient.Database("MONGODB_DATABASE").Collection(collection).UpdateOne(ctx, filter, update)
+		}
+		if err != nil {
+			return writes{}, err
+		}
+		return writes{matched: result.MatchedCount, modified: result.ModifiedCount, upserted: result.UpsertedCount}, nil
 	case *sql.DB:
 		mysqlDB := c
-		updateQuery := generateMySQLQuery(table, field, record, prevVal, newVal)
-		_, err := mysqlDB.Exec(updateQuery)
-		return err
+		query, args := mysqlUpdate(table, k, target, value)
+		result, err := mysqlDB.ExecContext(ctx, query, args...)
+		if err != nil {
+			return writes{}, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return writes{}, err
+		}
+		if k != upsert {
+			return writes{modified: affected}, nil
+		}
+		// ON DUPLICATE KEY UPDATE affects 1 row per insert, 2 per changed row
+		// and 0 per row left as it was
+		switch affected {
+		case 1:
+			return writes{upserted: 1}, nil
+		case 2:
+			return writes{matched: 1, modified: 1}, nil
+		default:
+			return writes{matched: 1}, nil
+		}
 	default:
-		return errors.New("unsupported client type")
+		return writes{}, errors.New("unsupported client type")
+	}
+}
+
+// mongoUpdate is the collection, filter and update document of an update of
+// a kind on the rows target stands for
+func mongoUpdate(table string, k kind, target create.Record, value int) (string, bson.M, bson.M) {
+	job := fmt.Sprintf("Updated %d", value)
+	key := bson.M{"Name": target.Name, "Year": target.Year}
+	switch k {
+	case byKey:
+		return table, key, bson.M{"$set": bson.M{"Job": job}}
+	case byFilter:
+		return table, bson.M{"Department": target.Department}, bson.M{"$set": bson.M{"Job": job}}
+	case increment:
+		return table, key, bson.M{"$inc": bson.M{"Earnings": value}}
+	default:
+		return upsertTable(table), key, bson.M{"$set": bson.M{"School": target.School, "Job": job, "Department": target.Department, "Earnings": target.Earnings}}
+	}
+}
+
+// mysqlUpdate is the statement and arguments of an update of a kind on the
+// rows target stands for
+func mysqlUpdate(table string, k kind, target create.Record, value int) (string, []interface{}) {
+	job := fmt.Sprintf("Updated %d", value)
+	switch k {
+	case byKey:
+		return "UPDATE " + table + " SET Job = ? WHERE Name = ? AND Year = ?", []interface{}{job, target.Name, target.Year}
+	case byFilter:
+		return "UPDATE " + table + " SET Job = ? WHERE Department = ?", []interface{}{job, target.Department}
+	case increment:
+		return "UPDATE " + table + " SET Earnings = Earnings + ? WHERE Name = ? AND Year = ?", []interface{}{value, target.Name, target.Year}
+	default:
+		query := "INSERT INTO " + upsertTable(table) + " (Name, School, Job, Department, Earnings, Year) VALUES (?, ?, ?, ?, ?, ?) " +
+			"ON DUPLICATE KEY UPDATE School = VALUES(School), Job = VALUES(Job), Department = VALUES(Department), Earnings = VALUES(Earnings)"
+		return query, []interface{}{target.Name, target.School, job, target.Department, target.Earnings, target.Year}
+	}
+}
+
+// updateQuery is the first update of a kind as explain runs it
+func updateQuery(table string, k kind, f rows) explain.Query {
+	target := f.keys[0]
+	switch k {
+	case byFilter:
+		target = f.filters[0]
+	case upsert:
+		target = f.upserts[0]
+	}
+	query, args := mysqlUpdate(table, k, target, 1)
+	collection, filter, update := mongoUpdate(table, k, target, 1)
+	return explain.Query{SQL: query, Args: args, Mongo: explain.Update(collection, filter, update, k == byFilter, k == upsert)}
+}
+
+// countMatches counts the MySQL rows an update of a kind selects
+func countMatches(ctx context.Context, mysqlDB *sql.DB, table string, k kind, target create.Record) (int64, error) {
+	var matched int64
+	var err error
+	if k == byFilter {
+		err = mysqlDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table+" WHERE Department = ?", target.Department).Scan(&matched)
+	} else {
+		err = mysqlDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table+" WHERE Name = ? AND Year = ?", target.Name, target.Year).Scan(&matched)
+	}
+	return matched, err
+}
+
+// completed read-modify-writes of the workers
+type completed struct {
+	mu        sync.Mutex
+	latencies []float64 // seconds
+	counts    writes
+}
+
+// readModifyWrites starts workers that each read rmwPerWorker of the hot rows
+// in turn and write back their Earnings plus one. pending is done once they
+// finished and failed is called with each of their errors.
+func readModifyWrites(ctx context.Context, client interface{}, table string, hot []create.Record, workers int, pending *sync.WaitGroup, failed func(error)) *completed {
+	done := &completed{}
+	for w := 0; w < workers; w++ {
+		pending.Add(1)
+		go func(w int) {
+			defer pending.Done()
+			for i := 0; i < rmwPerWorker; i++ {
+				key := hot[(w+i)%len(hot)]
+				var counts writes
+				start := time.Now()
+				err := runctx.Do(ctx, func(ctx context.Context) error {
+					var err error
+					counts, err = readModifyWrite(ctx, client, table, key)
+					return err
+				})
+				elapsed := time.Since(start)
+				if runctx.Stopped(err) {
+					return
+				}
+				if err != nil {
+					failed(err)
+					continue
+				}
+				done.mu.Lock()
+				done.latencies = append(done.latencies, elapsed.Seconds())
+				done.counts.add(counts)
+				done.mu.Unlock()
+			}
+		}(w)
 	}
+	return done
 }
 
-func multiThreadedUpdate(client interface{}, table, field, record, prevVal, newVal string) error {
+// readModifyWrite reads the Earnings of a row and writes them back plus one,
+// without a transaction, the way a client caching a row would
+func readModifyWrite(ctx context.Context, client interface{}, table string, key create.Record) (writes, error) {
 	switch c := client.(type) {
 	case *mongo.Client:
 		mongoClient := c
-		go func() {
-			err := singleThreadedUpdate(mongoClient, table, field, record, prevVal, newVal)
-			if err != nil {
-				fmt.Printf("Error updating %s in MongoDB: %v\n", table, err)
-			}
-		}()
-		return nil
+		collection := mongoClient.Database("MONGODB_DATABASE").Collection(table)
+		filter := bson.M{"Name": key.Name, "Year": key.Year}
+		var record create.Record
+		if err := collection.FindOne(ctx, filter).Decode(&record); err != nil {
+			return writes{}, err
+		}
+		result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"Earnings": record.Earnings + 1}})
+		if err != nil {
+			return writes{}, err
+		}
+		return writes{matched: result.MatchedCount, modified: result.ModifiedCount}, nil
 	case *sql.DB:
 		mysqlDB := c
-		go func() {
-			err := singleThreadedUpdate(mysqlDB, table, field, record, prevVal, newVal)
-			if err != nil {
-				fmt.Printf("Error updating %s in MySQL: %v\n", table, err)
-			}
-		}()
-		return nil
+		var earnings float64
+		err := mysqlDB.QueryRowContext(ctx, readEarnings(table), key.Name, key.Year).Scan(&earnings)
+		if err != nil {
+			return writes{}, err
+		}
+		result, err := mysqlDB.ExecContext(ctx, "UPDATE "+table+" SET Earnings = ? WHERE Name = ? AND Year = ?", earnings+1, key.Name, key.Year)
+		if err != nil {
+			return writes{}, err
+		}
+		modified, err := result.RowsAffected()
+		if err != nil {
+			return writes{}, err
+		}
+		// The read found the row, so the update selected it
+		return writes{matched: 1, modified: modified}, nil
 	default:
-		return errors.New("unsupported client type")
+		return writes{}, errors.New("unsupported client type")
 	}
 }
 
-func generateMongoDBQuery(collection, database, field, record, prevVal, newVal string) interface{} {
-	updateQuery := bson.M{
-		"$set": bson.M{
-			field: newVal,
-		},
-	}
-	return updateQuery
+// readEarnings is the MySQL read of a read-modify-write
+func readEarnings(table string) string {
+	return "SELECT Earnings FROM " + table + " WHERE Name = ? AND Year = ? LIMIT 1"
 }
 
-func generateMySQLQuery(table, field, record, prevVal, newVal string) string {
-	return fmt.Sprintf("UPDATE %s SET %s = '%s' WHERE Name = '%s' AND %s = '%s'", table, field, newVal, record, field, prevVal)
+// readQuery is the read of a read-modify-write as explain runs it
+func readQuery(table string, key create.Record) explain.Query {
+	return explain.Query{
+		SQL:   readEarnings(table),
+		Args:  []interface{}{key.Name, key.Year},
+		Mongo: explain.Find(table, bson.M{"Name": key.Name, "Year": key.Year}, nil, 1),
+	}
 }
 
-func plotTimeBarChart(title string, labels []string, mongoTimes, mysqlTimes []float64) error {
-	p := plot.New()
-
-	p.Title.Text = title
-	p.Y.Label.Text = "Time (s)"
-
-	bars1, err := plotter.NewBarChart(plotter.Values(mongoTimes), vg.Points(50))
-	if err != nil {
-		return err
+// lostUpdates compares the Earnings of the hot rows with the increments that
+// succeeded and returns how many were overwritten by concurrent writers
+func lostUpdates(ctx context.Context, client interface{}, table string, hot []create.Record, increments int64) (int64, error) {
+	var total float64
+	for _, key := range hot {
+		var earnings float64
+		err := runctx.Do(ctx, func(ctx context.Context) error {
+			switch c := client.(type) {
+			case *mongo.Client:
+				var record create.Record
+				err := c.Database("MONGODB_DATABASE").Collection(table).FindOne(ctx, bson.M{"Name": key.Name, "Year": key.Year}).Decode(&record)
+				earnings = record.Earnings
+				return err
+			case *sql.DB:
+				return c.QueryRowContext(ctx, readEarnings(table), key.Name, key.Year).Scan(&earnings)
+			default:
+				return errors.New("unsupported client type")
+			}
+		})
+		if err != nil {
+			return 0, err
+		}
+		total += earnings - key.Earnings
 	}
-	bars1.LineStyle.Width = vg.Length(0)
-	bars1.Color = plotter.DefaultLineStyle.Color
-	bars1.Offset = -vg.Points(25)
-	p.Add(bars1)
+	return increments - int64(total), nil
+}
 
-	bars2, err := plotter.NewBarChart(plotter.Values(mysqlTimes), vg.Points(50))
-	if err != nil {
-		return err
+// plotGraph draws the mean time of each update variant per table, the time
+// of the read-modify-writes and the latency distribution of updates by key
+func plotGraph(run *results.Run, tables []string) {
+	labels := make(map[string]string)
+	for _, v := range variants {
+		labels[v.operation] = v.label
+	}
+	variant := func(r results.Result) string { return labels[r.Operation] }
+	for _, table := range tables {
+		var rs []results.Result
+		for _, r := range run.Results {
+			if r.Table == table && labels[r.Operation] != "" {
+				rs = append(rs, r)
+			}
+		}
+		if len(rs) == 0 {
+			continue
+		}
+		if err := plot.Bars("update_"+table, "Time per update in "+dataset.Label(table), "Update", rs, variant, plot.Mean); err != nil {
+			fmt.Println("Error plotting update times:", err)
+		}
 	}
-	bars2.LineStyle.Width = vg.Length(0)
-	bars2.Color = plotter.DefaultLineStyle.Color
-	bars2.Offset = vg.Points(25)
-	p.Add(bars2)
-
-	p.Legend.Add("MongoDB", bars1)
-	p.Legend.Add("MySQL", bars2)
-	p.NominalX(labels...)
 
-	if err := p.Save(8*vg.Inch, 4*vg.Inch, "./PLOTSplot_update.png"); err != nil {
-		return err
+	var rmw, single []results.Result
+	for _, r := range run.Results {
+		switch r.Operation {
+		case "read-modify-write":
+			rmw = append(rmw, r)
+		case "update by key":
+			single = append(single, r)
+		}
+	}
+	title := fmt.Sprintf("Time taken for read-modify-writes (%d workers)", run.Config.Workers)
+	if err := plot.Bars("update_read_modify_write", title, "Table", rmw, label, plot.Duration); err != nil {
+		fmt.Println("Error plotting update times:", err)
+	}
+	if err := plot.Distributions("update", "Update by key", single, label); err != nil {
+		fmt.Println("Error plotting update latency distributions:", err)
 	}
+}
 
-	return nil
+// label names the table of a result with its size
+func label(r results.Result) string {
+	return dataset.Label(r.Table)
 }
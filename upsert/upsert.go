@@ -0,0 +1,342 @@
+// Package upsert compares the ways each backend inserts a record or updates it
+// when its key already exists: MySQL INSERT ... ON DUPLICATE KEY UPDATE and
+// REPLACE against MongoDB UpdateOne and ReplaceOne with upsert.
+package upsert
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"benchmarkDB/bench"
+	"benchmarkDB/create"
+	"benchmarkDB/dataset"
+	"benchmarkDB/explain"
+	"benchmarkDB/fixture"
+	"benchmarkDB/report/plot"
+	"benchmarkDB/results"
+	"benchmarkDB/runctx"
+	"benchmarkDB/server"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Options of the upsert workload
+type Options struct {
+	Operations int     // upserts per backend, table and method
+	HitRatio   float64 // share of the upserts on keys that already exist, 0 to 1
+}
+
+// Default options, set from the command line
+var Default = Options{Operations: 1000, HitRatio: 0.5}
+
+// Validate checks that there is something to run and the hit ratio is a share
+func (o Options) Validate() error {
+	if o.Operations < 1 {
+		return errors.New("operations must be at least 1")
+	}
+	if o.HitRatio < 0 || o.HitRatio > 1 {
+		return errors.New("hit ratio must be between 0 and 1")
+	}
+	return nil
+}
+
+// Seed of the keys upserted, the same for every backend and method
+const seed = 1
+
+// method of upserting, each compared with the method of the other backend
+// that has the same effect
+type method int
+
+const (
+	update  method = iota // change the given fields, UpdateOne vs ON DUPLICATE KEY UPDATE
+	replace               // replace the whole record, ReplaceOne vs REPLACE
+)
+
+var methods = []struct {
+	method    method
+	operation string
+}{
+	{update, "upsert (update)"},
+	{replace, "upsert (replace)"},
+}
+
+func Upsert() {
+	opts := Default
+	tables := []string{"table1", "table2", "table3", "table4"} // Representing MongoDB collections or MySQL tables
+
+	// Connected to both backends, the upserts run on tables of their own
+	ctx, b := bench.Start(nil)
+
+	backends := []struct {
+		name   string
+		client interface{}
+	}{{"MongoDB", b.Mongo}, {"MySQL", b.MySQL}}
+
+	run := results.NewRun("upsert", results.Config{OperationCount: opts.Operations, HitRatio: opts.HitRatio})
+	run.Environment.Servers = b.Servers()
+	fmt.Printf("************Performing upserts (%d per table, %.0f%% on existing keys)***************\n", opts.Operations, opts.HitRatio*100)
+	for _, table := range tables {
+		if ctx.Err() != nil {
+			break
+		}
+		seeded, err := dataset.Load(table)
+		if err != nil {
+			fmt.Println("Error loading", table+":", err)
+			run.AddError("", table, "", err)
+			continue
+		}
+		seeded = unique(seeded)
+		records, hits := workload(seeded, opts)
+		fmt.Printf("%s: %d upserts, %d on existing keys\n", table, len(records), hits)
+
+		for _, m := range methods {
+			for _, backend := range backends {
+				if ctx.Err() != nil {
+					break
+				}
+				// Every method starts from the seeded records, not timed
+				err := fixture.CreateKeyed(ctx, backend.client, keyedTable(table))
+				if err == nil {
+					err = fixture.Insert(ctx, backend.client, keyedTable(table), seeded)
+				}
+				if runctx.Stopped(err) {
+					break
+				}
+				if err != nil {
+					fmt.Printf("Error seeding %s %s: %v\n", backend.name, keyedTable(table), err)
+					run.AddError(backend.name, table, m.operation, err)
+					continue
+				}
+
+				// Untimed, explaining an upsert does not write
+				plan := explain.Run(ctx, backend.client, upsertQuery(table, m.method, records[0]))
+				watch := server.Watch(ctx, backend.client, server.Report(run, backend.name, table))
+				latencies, counts, errs := timedUpserts(ctx, backend.client, table, m.method, records)
+				bench.PrintErrors(fmt.Sprintf("running %s on %s in %s", m.operation, table, backend.name), errs)
+				r := results.Iterations(backend.name, table, m.operation, latencies)
+				r.Server = watch.Stop()
+				r.Plan = plan
+				r.Rows = len(seeded)
+				r.Matched, r.Modified, r.Upserted = counts.matched, counts.modified, counts.upserted
+				run.Add(r, errs...)
+				if len(latencies) > 0 {
+					fmt.Printf("    Time taken for %s %s in %s: %v mean (%d matched, %d modified, %d upserted)\n",
+						backend.name, m.operation, table, results.Micros(r.Latency.Mean), counts.matched, counts.modified, counts.upserted)
+				}
+			}
+		}
+	}
+	fmt.Println("*************************************************************")
+
+	// Plotting the graph
+	run.Finished = time.Now()
+	plotGraph(run)
+	b.After(func() error {
+		var errs []error
+		for _, table := range tables {
+			for _, backend := range backends {
+				if err := fixture.Drop(context.Background(), backend.client, keyedTable(table)); err != nil {
+					errs = append(errs, fmt.Errorf("dropping %s %s: %v", backend.name, keyedTable(table), err))
+				}
+			}
+		}
+		return errors.Join(errs...)
+	})
+	b.Finish(run)
+}
+
+// keyedTable is the copy of a table with a unique key on Name and Year the
+// upserts run against
+func keyedTable(table string) string {
+	return table + "_upsert"
+}
+
+// unique drops the records repeating the Name and Year of an earlier one
+func unique(records []create.Record) []create.Record {
+	type key struct {
+		name string
+		year int
+	}
+	seen := make(map[key]bool)
+	var kept []create.Record
+	for _, record := range records {
+		k := key{record.Name, record.Year}
+		if !seen[k] {
+			seen[k] = true
+			kept = append(kept, record)
+		}
+	}
+	return kept
+}
+
+// workload generates the records to upsert, a HitRatio share of them with
+// the key of a seeded record and the rest with keys no other record has. It
+// returns how many have existing keys.
+func workload(seeded []create.Record, opts Options) ([]create.Record, int) {
+	r := rand.New(rand.NewSource(seed))
+	records := make([]create.Record, opts.Operations)
+	hits := 0
+	for i := range records {
+		record := create.Record{
+			Name:       fmt.Sprintf("Upsert new-%d", i),
+			School:     "Upsert benchmark",
+			Job:        fmt.Sprintf("Upserted %d", i),
+			Department: "Upsert benchmark",
+			Earnings:   float64(20000 + r.Intn(100000)),
+			Year:       2100,
+		}
+		if len(seeded) > 0 && r.Float64() < opts.HitRatio {
+			existing := seeded[r.Intn(len(seeded))]
+			record.Name, record.Year = existing.Name, existing.Year
+			hits++
+		}
+		records[i] = record
+	}
+	return records, hits
+}
+
+// writes counts the rows or documents upserts matched, modified and inserted
+type writes struct {
+	matched, modified, upserted int64
+}
+
+// timedUpserts upserts the records one at a time and returns the latency of
+// each successful upsert in seconds, the rows they matched, modified and
+// inserted and the errors of the failed ones. It returns early when the run
+// is stopped.
+func timedUpserts(ctx context.Context, client interface{}, table string, m method, records []create.Record) ([]float64, writes, []error) {
+	latencies := make([]float64, 0, len(records))
+	var counts writes
+	var errs []error
+	for _, record := range records {
+		var w writes
+		start := time.Now()
+		err := runctx.Do(ctx, func(ctx context.Context) error {
+			var err error
+			w, err = upsertRecord(ctx, client, table, m, record)
+			return err
+		})
+		elapsed := time.Since(start)
+		if runctx.Stopped(err) {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		counts.matched += w.matched
+		counts.modified += w.modified
+		counts.upserted += w.upserted
+		latencies = append(latencies, elapsed.Seconds())
+	}
+	return latencies, counts, errs
+}
+
+// upsertRecord inserts the record, or changes the stored record with its
+// Name and Year
+func upsertRecord(ctx context.Context, client interface{}, table string, m method, record create.Record) (writes, error) {
+	switch c := client.(type) {
+	case *mongo.Client:
+		mongoClient := c
+		collection := mongoClient.Database("MONGODB_DATABASE").Collection(keyedTable(table))
+		filter, change := mongoUpsert(m, record)
+		var result *mongo.UpdateResult
+		var err error
+		if m == update {
+			result, err = collection.UpdateOne(ctx, filter, change, options.Update().SetUpsert(true))
+		} else {
+			result, err = collection.ReplaceOne(ctx, filter, change, options.Replace().SetUpsert(true))
+		}
+		if err != nil {
+			return writes{}, err
+		}
+		return writes{matched: result.MatchedCount, modified: result.ModifiedCount, upserted: result.UpsertedCount}, nil
+	case *sql.DB:
+		mysqlDB := c
+		result, err := mysqlDB.ExecContext(ctx, mysqlUpsert(table, m), mysqlArgs(record)...)
+		if err != nil {
+			return writes{}, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return writes{}, err
+		}
+		// Both affect 1 row per insert. ON DUPLICATE KEY UPDATE affects 2 per
+		// changed row and 0 per row left as it was, REPLACE deletes the stored
+		// row and inserts the new one, affecting 2.
+		switch affected {
+		case 1:
+			return writes{upserted: 1}, nil
+		case 2:
+			return writes{matched: 1, modified: 1}, nil
+		default:
+			return writes{matched: 1}, nil
+		}
+	default:
+		return writes{}, errors.New("unsupported client type")
+	}
+}
+
+// mongoUpsert is the filter of an upsert by a method and the update or, when
+// replacing, the document it writes
+func mongoUpsert(m method, record create.Record) (bson.M, interface{}) {
+	filter := bson.M{"Name": record.Name, "Year": record.Year}
+	if m == update {
+		return filter, bson.M{"$set": bson.M{"School": record.School, "Job": record.Job, "Department": record.Department, "Earnings": record.Earnings}}
+	}
+	return filter, record
+}
+
+// mysqlUpsert is the MySQL statement of an upsert by a method
+func mysqlUpsert(table string, m method) string {
+	if m == update {
+		return "INSERT INTO " + keyedTable(table) + " (Name, School, Job, Department, Earnings, Year) VALUES (?, ?, ?, ?, ?, ?) " +
+			"ON DUPLICATE KEY UPDATE School = VALUES(School), Job = VALUES(Job), Department = VALUES(Department), Earnings = VALUES(Earnings)"
+	}
+	return "REPLACE INTO " + keyedTable(table) + " (Name, School, Job, Department, Earnings, Year) VALUES (?, ?, ?, ?, ?, ?)"
+}
+
+func mysqlArgs(record create.Record) []interface{} {
+	return []interface{}{record.Name, record.School, record.Job, record.Department, record.Earnings, record.Year}
+}
+
+// upsertQuery is the upsert of a record as explain runs it
+func upsertQuery(table string, m method, record create.Record) explain.Query {
+	filter, change := mongoUpsert(m, record)
+	return explain.Query{
+		SQL:   mysqlUpsert(table, m),
+		Args:  mysqlArgs(record),
+		Mongo: explain.Update(keyedTable(table), filter, change, false, true),
+	}
+}
+
+// plotGraph draws the mean time of each upsert method per table and the
+// latency distributions of the upserts
+func plotGraph(run *results.Run) {
+	table := func(r results.Result) string { return dataset.Label(r.Table) }
+	for _, m := range methods {
+		var rs []results.Result
+		for _, r := range run.Results {
+			if r.Operation == m.operation {
+				rs = append(rs, r)
+			}
+		}
+		name := "upsert_update"
+		if m.method == replace {
+			name = "upsert_replace"
+		}
+		title := fmt.Sprintf("Time per %s, %.0f%% on existing keys", m.operation, run.Config.HitRatio*100)
+		if err := plot.Bars(name, title, "Table", rs, table, plot.Mean); err != nil {
+			fmt.Println("Error saving plot:", err)
+		}
+		if err := plot.Distributions(name, m.operation, rs, table); err != nil {
+			fmt.Println("Error saving plot:", err)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package ycsb
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync"
+
+	"benchmarkDB/create"
+)
+
+// Zipfian constant used by YCSB
+const zipfianConstant = 0.99
+
+// Key identifies a record. Name alone is not unique in the dataset so Name and
+// Year are used together, the same way the delete benchmark does.
+type Key struct {
+	Name string
+	Year int
+}
+
+// keyspace holds the keys of a table, including the ones inserted during the run
+type keyspace struct {
+	mu       sync.Mutex
+	keys     []Key
+	inserted int
+}
+
+func newKeyspace(records []create.Record) *keyspace {
+	keys := make([]Key, len(records))
+	for i, record := range records {
+		keys[i] = Key{Name: record.Name, Year: record.Year}
+	}
+	return &keyspace{keys: keys}
+}
+
+func (k *keyspace) size() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.keys)
+}
+
+func (k *keyspace) get(i int) Key {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.keys[i]
+}
+
+// next returns a fresh key for an insert. The key is only visible to readers
+// once add has been called, i.e. after the insert succeeded.
+func (k *keyspace) next() Key {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.inserted++
+	return Key{Name: fmt.Sprintf("ycsb-user%d", k.inserted), Year: 2024}
+}
+
+func (k *keyspace) add(key Key) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = append(k.keys, key)
+}
+
+// keyChooser picks an index in [0, n) for the next operation
+type keyChooser interface {
+	next(r *rand.Rand, n int) int
+}
+
+// newKeyChooser returns the chooser of a request distribution. expected is
+// the number of keys the keyspace is expected to grow to during the run.
+func newKeyChooser(distribution string, expected int) (keyChooser, error) {
+	switch distribution {
+	case Uniform:
+		return uniformChooser{}, nil
+	case Zipfian:
+		return &scrambledZipfianChooser{items: expected}, nil
+	case Latest:
+		return &latestChooser{}, nil
+	default:
+		return nil, fmt.Errorf("unknown request distribution %q", distribution)
+	}
+}
+
+type uniformChooser struct{}
+
+func (uniformChooser) next(r *rand.Rand, n int) int {
+	return r.Intn(n)
+}
+
+// zipfian implements the generator from "Quickly Generating Billion-Record
+// Synthetic Databases" (Gray et al.), as used by YCSB. Item 0 is the most popular.
+type zipfian struct {
+	mu         sync.Mutex
+	items      int
+	zetan      float64
+	zeta2theta float64
+	alpha      float64
+	eta        float64
+}
+
+func (z *zipfian) next(r *rand.Rand, n int) int {
+	// The zeta constants are undefined for tiny keyspaces
+	if n < 3 {
+		return r.Intn(n)
+	}
+
+	z.mu.Lock()
+	if n != z.items {
+		z.resize(n)
+	}
+	zetan, eta, alpha, items := z.zetan, z.eta, z.alpha, z.items
+	z.mu.Unlock()
+
+	u := r.Float64()
+	uz := u * zetan
+	if uz < 1 {
+		return 0
+	}
+	if uz < 1+math.Pow(0.5, zipfianConstant) {
+		return 1
+	}
+	i := int(float64(items) * math.Pow(eta*u-eta+1, alpha))
+	if i >= items {
+		i = items - 1
+	}
+	return i
+}
+
+// resize updates the zeta constant incrementally when the keyspace grows
+func (z *zipfian) resize(n int) {
+	if z.items == 0 || n < z.items {
+		z.zetan = 0
+		z.items = 0
+	}
+	for i := z.items + 1; i <= n; i++ {
+		z.zetan += 1 / math.Pow(float64(i), zipfianConstant)
+	}
+	z.items = n
+	z.zeta2theta = 1 + 1/math.Pow(2, zipfianConstant)
+	z.alpha = 1 / (1 - zipfianConstant)
+	z.eta = (1 - math.Pow(2/float64(n), 1-zipfianConstant)) / (1 - z.zeta2theta/z.zetan)
+}
+
+// scrambledZipfianChooser spreads the popular items over the keyspace instead
+// of clustering them at the start of the table. Like YCSB it draws from a
+// fixed number of items, the keys expected once the run's inserts are done,
+// so the popular keys stay the same while the keyspace grows. Items not
+// inserted yet are drawn again and, as in YCSB, keys beyond the expected ones
+// are never drawn.
+type scrambledZipfianChooser struct {
+	zipfian
+	items int
+}
+
+func (s *scrambledZipfianChooser) next(r *rand.Rand, n int) int {
+	items := s.items
+	if items <= 0 {
+		items = n
+	}
+	for attempt := 0; attempt < 100; attempt++ {
+		if i := scramble(s.zipfian.next(r, items), items); i < n {
+			return i
+		}
+	}
+	// Far fewer keys than expected, spread the item over the ones there are
+	return scramble(s.zipfian.next(r, items), n)
+}
+
+// scramble maps item i of n to a position in [0, n) by its FNV hash
+func scramble(i, n int) int {
+	h := fnv.New64a()
+	var b [8]byte
+	for j := range b {
+		b[j] = byte(uint64(i) >> (8 * j))
+	}
+	h.Write(b[:])
+	return int(h.Sum64() % uint64(n))
+}
+
+// latestChooser favours the most recently inserted keys
+type latestChooser struct {
+	zipfian
+}
+
+func (l *latestChooser) next(r *rand.Rand, n int) int {
+	return n - 1 - l.zipfian.next(r, n)
+}
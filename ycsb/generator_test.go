@@ -0,0 +1,126 @@
+package ycsb
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestChoosersStayInRange(t *testing.T) {
+	for _, distribution := range []string{Uniform, Zipfian, Latest} {
+		for _, n := range []int{1, 2, 3, 10, 1000} {
+			chooser, err := newKeyChooser(distribution, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := rand.New(rand.NewSource(1))
+			for i := 0; i < 10000; i++ {
+				if k := chooser.next(r, n); k < 0 || k >= n {
+					t.Fatalf("%s over %d keys chose %d", distribution, n, k)
+				}
+			}
+		}
+	}
+}
+
+func TestScrambledZipfianBeforeInserts(t *testing.T) {
+	// Expecting twice the keys there are, only the existing ones are chosen
+	chooser, _ := newKeyChooser(Zipfian, 2000)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		if k := chooser.next(r, 1000); k < 0 || k >= 1000 {
+			t.Fatalf("chose %d of 1000 keys", k)
+		}
+	}
+}
+
+func TestUnknownDistribution(t *testing.T) {
+	if _, err := newKeyChooser("hotspot", 10); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+// counts draws n keys times from chooser
+func counts(chooser keyChooser, n, draws int, seed int64) []int {
+	r := rand.New(rand.NewSource(seed))
+	c := make([]int, n)
+	for i := 0; i < draws; i++ {
+		c[chooser.next(r, n)]++
+	}
+	return c
+}
+
+// hottest is the most drawn key
+func hottest(c []int) int {
+	best := 0
+	for k := range c {
+		if c[k] > c[best] {
+			best = k
+		}
+	}
+	return best
+}
+
+// share of the draws that went to keys
+func share(c []int, keys func(k int) bool) float64 {
+	var in, total int
+	for k, n := range c {
+		total += n
+		if keys(k) {
+			in += n
+		}
+	}
+	return float64(in) / float64(total)
+}
+
+func TestZipfianSkew(t *testing.T) {
+	const n, draws = 1000, 100000
+	c := counts(&zipfian{}, n, draws, 1)
+	if hottest(c) != 0 {
+		t.Errorf("hottest item is %d, want 0", hottest(c))
+	}
+	// With a constant of 0.99 the first 10% of 1000 items get about 69% of
+	// the draws
+	if s := share(c, func(k int) bool { return k < n/10 }); s < 0.6 || s > 0.78 {
+		t.Errorf("first 10%% of the items got %.2f of the draws", s)
+	}
+
+	u := counts(uniformChooser{}, n, draws, 1)
+	if s := share(u, func(k int) bool { return k < n/10 }); s < 0.08 || s > 0.12 {
+		t.Errorf("uniform: first 10%% of the keys got %.2f of the draws", s)
+	}
+}
+
+func TestScrambledZipfianSpreadsHotKeys(t *testing.T) {
+	const n = 1000
+	chooser, _ := newKeyChooser(Zipfian, n)
+	c := counts(chooser, n, 100000, 1)
+	if s := share(c, func(k int) bool { return k < n/10 }); s > 0.4 {
+		t.Errorf("first 10%% of the keys got %.2f of the draws, the hot keys are not spread", s)
+	}
+}
+
+func TestScrambledZipfianHotKeysStableWhileGrowing(t *testing.T) {
+	chooser, _ := newKeyChooser(Zipfian, 1200)
+	before := counts(chooser, 1000, 100000, 1)
+	after := counts(chooser, 1200, 100000, 2)
+	if hottest(before) != hottest(after) {
+		t.Errorf("hottest key moved from %d to %d as keys were inserted", hottest(before), hottest(after))
+	}
+	// The popular keys keep their share as the keyspace grows
+	hot := hottest(before)
+	if b, a := share(before, func(k int) bool { return k == hot }), share(after, func(k int) bool { return k == hot }); a < 0.8*b {
+		t.Errorf("hottest key went from %.3f to %.3f of the draws", b, a)
+	}
+}
+
+func TestLatestFavoursNewestKeys(t *testing.T) {
+	chooser, _ := newKeyChooser(Latest, 0)
+	c := counts(chooser, 1000, 100000, 1)
+	if hottest(c) != 999 {
+		t.Errorf("hottest key is %d, want the newest 999", hottest(c))
+	}
+	c = counts(chooser, 1100, 100000, 1)
+	if hottest(c) != 1099 {
+		t.Errorf("after inserts hottest key is %d, want the newest 1099", hottest(c))
+	}
+}
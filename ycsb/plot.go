@@ -0,0 +1,223 @@
+package ycsb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"benchmarkDB/histogram"
+	"benchmarkDB/report/plot"
+	"benchmarkDB/results"
+
+	"gonum.org/v1/plot/plotter"
+)
+
+// percentileSeries is one line of a percentile distribution plot
+type percentileSeries struct {
+	backend   string
+	op        string
+	histogram *histogram.Histogram
+}
+
+// percentileTicks label the x axis of percentile plots, which is 1/(1-q)
+var percentileTicks = []plot.Tick{
+	{Value: 1, Label: "0%"},
+	{Value: 10, Label: "90%"},
+	{Value: 100, Label: "99%"},
+	{Value: 1000, Label: "99.9%"},
+	{Value: 10000, Label: "99.99%"},
+	{Value: 100000, Label: "99.999%"},
+}
+
+// plotPercentiles draws latency by percentile for every backend and operation
+// type run against a table. The x axis is 1/(1-q) on a log scale so the tail
+// (99%, 99.9%, ...) gets as much room as the median.
+func plotPercentiles(workload, table string, series []percentileSeries) error {
+	// Each backend keeps its colour, its second operation type is dashed
+	var lines []plot.Line
+	first := make(map[string]string)
+	for _, s := range series {
+		if _, ok := first[s.backend]; !ok {
+			first[s.backend] = s.op
+		}
+		line := plot.Line{Label: s.backend + " " + s.op, Dashed: s.op != first[s.backend]}
+		for _, point := range s.histogram.PercentileDistribution(5) {
+			line.Points = append(line.Points, plotter.XY{
+				X: 1 / (1 - point.Quantile),
+				Y: float64(point.Value) / 1000,
+			})
+		}
+		lines = append(lines, line)
+	}
+
+	chart := plot.Chart{
+		Title:  fmt.Sprintf("Latency by percentile, workload %s, %s", workload, table),
+		XLabel: "Percentile",
+		YLabel: "Latency (ms)",
+		LogX:   true,
+		XTicks: percentileTicks,
+	}
+	return plot.Lines(fmt.Sprintf("ycsb_%s_%s_percentiles", strings.ToLower(workload), table), chart, lines)
+}
+
+// plotTimeSeries draws the sampled throughput and the p50/p99 latency of every
+// backend over the course of the run on a table
+func plotTimeSeries(workload, table string, series []results.Series) error {
+	var throughput, latency []plot.Line
+	for _, s := range series {
+		ops := plot.Line{Label: s.Backend}
+		p50 := plot.Line{Label: s.Backend + " p50"}
+		p99 := plot.Line{Label: s.Backend + " p99", Dashed: true}
+		for _, sample := range s.Samples {
+			ops.Points = append(ops.Points, plotter.XY{X: sample.ElapsedSeconds, Y: sample.Throughput})
+			p50.Points = append(p50.Points, plotter.XY{X: sample.ElapsedSeconds, Y: sample.P50 / 1000})
+			p99.Points = append(p99.Points, plotter.XY{X: sample.ElapsedSeconds, Y: sample.P99 / 1000})
+		}
+		throughput = append(throughput, ops)
+		latency = append(latency, p50, p99)
+	}
+
+	name := fmt.Sprintf("ycsb_%s_%s", strings.ToLower(workload), table)
+	err := plot.Lines(name+"_throughput", plot.Chart{
+		Title:  fmt.Sprintf("Throughput over time, workload %s, %s", workload, table),
+		XLabel: "Elapsed time (s)",
+		YLabel: "Throughput (ops/sec)",
+	}, throughput)
+	if err != nil {
+		return err
+	}
+	return plot.Lines(name+"_latency", plot.Chart{
+		Title:  fmt.Sprintf("Latency over time, workload %s, %s", workload, table),
+		XLabel: "Elapsed time (s)",
+		YLabel: "Latency (ms)",
+	}, latency)
+}
+
+// plotScalability draws throughput and p50/p99 latency against the number of
+// workers for every backend run against a table
+func plotScalability(workload, table string, runResults []results.Result) error {
+	var throughput, latency []plot.Line
+	var ticks []plot.Tick
+	for _, backend := range []string{"MongoDB", "MySQL"} {
+		ops := plot.Line{Label: backend, Markers: true}
+		p50 := plot.Line{Label: backend + " p50", Markers: true}
+		p99 := plot.Line{Label: backend + " p99", Markers: true, Dashed: true}
+		for _, r := range runResults {
+			if r.Backend != backend || r.Table != table || r.Operation != "all" {
+				continue
+			}
+			ops.Points = append(ops.Points, plotter.XY{X: float64(r.Workers), Y: r.Throughput})
+			p50.Points = append(p50.Points, plotter.XY{X: float64(r.Workers), Y: r.Latency.P50 / 1000})
+			p99.Points = append(p99.Points, plotter.XY{X: float64(r.Workers), Y: r.Latency.P99 / 1000})
+			ticks = addTick(ticks, r.Workers)
+		}
+		throughput = append(throughput, ops)
+		latency = append(latency, p50, p99)
+	}
+
+	// Worker counts usually double, so space them evenly
+	name := fmt.Sprintf("ycsb_%s_%s_sweep", strings.ToLower(workload), table)
+	err := plot.Lines(name+"_throughput", plot.Chart{
+		Title:  fmt.Sprintf("Throughput vs. concurrency, workload %s, %s", workload, table),
+		XLabel: "Workers",
+		YLabel: "Throughput (ops/sec)",
+		LogX:   true,
+		XTicks: ticks,
+	}, throughput)
+	if err != nil {
+		return err
+	}
+	return plot.Lines(name+"_latency", plot.Chart{
+		Title:  fmt.Sprintf("Latency vs. concurrency, workload %s, %s", workload, table),
+		XLabel: "Workers",
+		YLabel: "Latency (ms)",
+		LogX:   true,
+		XTicks: ticks,
+	}, latency)
+}
+
+// plotPoolScaling draws throughput and the mean time an operation waited for
+// a connection against the pool size, one line per backend
+func plotPoolScaling(workload, table string, runResults []results.Result) error {
+	var throughput, wait []plot.Line
+	var ticks []plot.Tick
+	for _, backend := range []string{"MongoDB", "MySQL"} {
+		ops := plot.Line{Label: backend, Markers: true}
+		waits := plot.Line{Label: backend, Markers: true}
+		for _, r := range runResults {
+			if r.Backend != backend || r.Table != table || r.Operation != "all" || r.Pool == nil || r.Operations == 0 {
+				continue
+			}
+			ops.Points = append(ops.Points, plotter.XY{X: float64(r.PoolSize), Y: r.Throughput})
+			waits.Points = append(waits.Points, plotter.XY{X: float64(r.PoolSize), Y: r.Pool.WaitSeconds * 1000 / float64(r.Operations)})
+			ticks = addTick(ticks, r.PoolSize)
+		}
+		throughput = append(throughput, ops)
+		wait = append(wait, waits)
+	}
+
+	// Pool sizes usually double, so space them evenly
+	name := fmt.Sprintf("ycsb_%s_%s_pool_sweep", strings.ToLower(workload), table)
+	err := plot.Lines(name+"_throughput", plot.Chart{
+		Title:  fmt.Sprintf("Throughput vs. pool size, workload %s, %s", workload, table),
+		XLabel: "Pool size (connections)",
+		YLabel: "Throughput (ops/sec)",
+		LogX:   true,
+		XTicks: ticks,
+	}, throughput)
+	if err != nil {
+		return err
+	}
+	return plot.Lines(name+"_wait", plot.Chart{
+		Title:  fmt.Sprintf("Connection wait vs. pool size, workload %s, %s", workload, table),
+		XLabel: "Pool size (connections)",
+		YLabel: "Mean wait per operation (ms)",
+		LogX:   true,
+		XTicks: ticks,
+	}, wait)
+}
+
+func addTick(ticks []plot.Tick, workers int) []plot.Tick {
+	for _, t := range ticks {
+		if t.Value == float64(workers) {
+			return ticks
+		}
+	}
+	return append(ticks, plot.Tick{Value: float64(workers), Label: strconv.Itoa(workers)})
+}
+
+// plotDatasetScaling draws p50/p99 latency and throughput against the number
+// of rows in each table, one line per backend
+func plotDatasetScaling(workload string, runResults []results.Result) error {
+	var latency, throughput []plot.Line
+	for _, backend := range []string{"MongoDB", "MySQL"} {
+		p50 := plot.Line{Label: backend + " p50", Markers: true}
+		p99 := plot.Line{Label: backend + " p99", Markers: true, Dashed: true}
+		ops := plot.Line{Label: backend, Markers: true}
+		for _, r := range runResults {
+			if r.Backend != backend || r.Operation != "all" || r.Rows == 0 {
+				continue
+			}
+			p50.Points = append(p50.Points, plotter.XY{X: float64(r.Rows), Y: r.Latency.P50 / 1000})
+			p99.Points = append(p99.Points, plotter.XY{X: float64(r.Rows), Y: r.Latency.P99 / 1000})
+			ops.Points = append(ops.Points, plotter.XY{X: float64(r.Rows), Y: r.Throughput})
+		}
+		latency = append(latency, p50, p99)
+		throughput = append(throughput, ops)
+	}
+
+	name := fmt.Sprintf("ycsb_%s_scaling", strings.ToLower(workload))
+	err := plot.Lines(name+"_latency", plot.Chart{
+		Title:  fmt.Sprintf("Latency vs. dataset size, workload %s", workload),
+		XLabel: "Rows in table",
+		YLabel: "Latency (ms)",
+	}, latency)
+	if err != nil {
+		return err
+	}
+	return plot.Lines(name+"_throughput", plot.Chart{
+		Title:  fmt.Sprintf("Throughput vs. dataset size, workload %s", workload),
+		XLabel: "Rows in table",
+		YLabel: "Throughput (ops/sec)",
+	}, throughput)
+}
@@ -0,0 +1,110 @@
+package ycsb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"benchmarkDB/bench"
+	"benchmarkDB/create"
+	"benchmarkDB/dataset"
+	"benchmarkDB/fixture"
+	"benchmarkDB/pool"
+)
+
+// DefaultPoolSizes is the sequence of pool sizes of a pool sweep
+var DefaultPoolSizes = []int{1, 2, 4, 8, 16, 32}
+
+// RunPoolSweep runs a core workload with the same number of workers against
+// the given tables on both MongoDB and MySQL, once per pool size, and plots
+// throughput and the time operations waited for a connection against it
+func RunPoolSweep(name string, opts Options, tables []string, sizes []int) {
+	workload, err := Lookup(name)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if len(sizes) == 0 {
+		sizes = DefaultPoolSizes
+	}
+	if err := opts.Validate(); err != nil {
+		fmt.Println("Invalid options:", err)
+		return
+	}
+
+	// Clients for the fixtures only, each pool size runs on clients of its own
+	ctx, b := bench.Start(tables)
+
+	seed := time.Now().UnixNano()
+	run := newPoolSweepRecorder(workload, opts, sizes)
+	run.run.Environment.Servers = b.Servers()
+
+	fmt.Printf("************Sweeping pool sizes for YCSB workload %s (%s)***************\n", workload.Name, workload.Description)
+	fmt.Println(describeOptions(opts))
+	for _, table := range tables {
+		if ctx.Err() != nil {
+			break
+		}
+		records, err := dataset.Load(table)
+		if err != nil {
+			fmt.Printf("Error loading keys for %s: %v\n", table, err)
+			run.run.AddError("", table, "load keys", err)
+			continue
+		}
+
+		for i, size := range sizes {
+			if ctx.Err() != nil {
+				break
+			}
+			// Every pool size starts from the seed data rather than from what the
+			// previous one inserted and deleted
+			if i > 0 {
+				if err := fixture.Setup(ctx, b.Mongo, b.MySQL, []string{table}); err != nil {
+					if ctx.Err() == nil {
+						fmt.Printf("Error resetting %s: %v\n", table, err)
+						run.run.AddError("", table, "reset", err)
+					}
+					break
+				}
+			}
+			fmt.Printf("Pool of %d connections\n", size)
+			for _, backend := range []string{"MongoDB", "MySQL"} {
+				result, err := runPooled(ctx, backend, pool.Default.Sized(size), table, workload, records, opts, seed)
+				if err != nil {
+					fmt.Printf("Error running workload %s on %s %s with a pool of %d: %v\n", workload.Name, backend, table, size, err)
+					run.run.AddError(backend, table, "all", err)
+					continue
+				}
+				result.PoolSize = size
+				printResult(backend, table, workload, result)
+				fmt.Printf("    %s\n", result.Pool)
+				run.add(backend, table, result)
+			}
+		}
+	}
+	fmt.Println("*************************************************************")
+
+	b.After(run.save)
+	b.Finish(run.run)
+}
+
+// runPooled runs the workload on a client of its own with the given pools,
+// a MongoDB pool cannot be resized once connected. The client starts with the
+// connection its ping opened, so the first operations also wait for the
+// others to be opened.
+func runPooled(ctx context.Context, backend string, connections pool.Config, table string, workload Workload, records []create.Record, opts Options, seed int64) (Result, error) {
+	if backend == "MongoDB" {
+		mongoClient, err := bench.Mongo(ctx, connections)
+		if err != nil {
+			return Result{}, err
+		}
+		defer bench.DisconnectMongo(mongoClient)
+		return Run(ctx, mongoClient, table, workload, records, opts, seed)
+	}
+	mysqlDB, err := bench.MySQL(ctx, connections)
+	if err != nil {
+		return Result{}, err
+	}
+	defer mysqlDB.Close()
+	return Run(ctx, mysqlDB, table, workload, records, opts, seed)
+}
@@ -0,0 +1,224 @@
+package ycsb
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"benchmarkDB/histogram"
+	"benchmarkDB/results"
+)
+
+// taggedHistogram is one interval of the histogram log
+type taggedHistogram struct {
+	tag       string
+	start     time.Time
+	duration  time.Duration
+	histogram *histogram.Histogram
+}
+
+// runRecorder collects the results of every backend and table of a workload
+// run so they can be written out together
+type runRecorder struct {
+	run        *results.Run
+	histograms []taggedHistogram
+	series     map[string][]percentileSeries // keyed by table
+	interval   time.Duration
+	timeSeries map[string][]results.Series // keyed by table
+	tables     []string
+}
+
+func newRunRecorder(workload Workload, opts Options) *runRecorder {
+	return newRecorder("ycsb-"+strings.ToLower(workload.Name), workload, opts)
+}
+
+// newSweepRecorder records a concurrency sweep, where every backend and table
+// is run once per worker count
+func newSweepRecorder(workload Workload, opts Options, workers []int) *runRecorder {
+	rr := newRecorder("ycsb-"+strings.ToLower(workload.Name)+"-sweep", workload, opts)
+	rr.run.Config.Workers = 0
+	rr.run.Config.Sweep = workers
+	return rr
+}
+
+// newPoolSweepRecorder records a pool sweep, where every backend and table is
+// run once per pool size at the same worker count
+func newPoolSweepRecorder(workload Workload, opts Options, sizes []int) *runRecorder {
+	rr := newRecorder("ycsb-"+strings.ToLower(workload.Name)+"-pool-sweep", workload, opts)
+	rr.run.Config.PoolSizes = sizes
+	return rr
+}
+
+func newRecorder(benchmark string, workload Workload, opts Options) *runRecorder {
+	config := results.Config{
+		Workload:       workload.Name,
+		OperationCount: opts.OperationCount,
+		TargetRate:     opts.TargetRate,
+		Workers:        opts.Workers,
+	}
+	if opts.Duration > 0 {
+		config.Duration = opts.Duration.String()
+	}
+	if opts.SampleInterval > 0 {
+		config.SampleInterval = opts.SampleInterval.String()
+	}
+
+	return &runRecorder{
+		run:        results.NewRun(benchmark, config),
+		series:     make(map[string][]percentileSeries),
+		interval:   opts.SampleInterval,
+		timeSeries: make(map[string][]results.Series),
+	}
+}
+
+// add records the result of one backend on one table, per operation type and overall
+func (rr *runRecorder) add(backend, table string, result Result) {
+	start := time.Now().Add(-result.Duration)
+	if !slices.Contains(rr.tables, table) {
+		rr.tables = append(rr.tables, table)
+	}
+
+	// A pool sweep runs the same worker count once per pool size
+	suffix := ""
+	if result.PoolSize > 0 {
+		suffix = fmt.Sprintf("/p%d", result.PoolSize)
+	}
+	for _, op := range operationTypes(result) {
+		h := result.latency(op)
+		r := summarize(backend, table, op, result, h, int64(result.Failures[op]), int64(result.TimedOut[op]))
+		r.Plan = result.Plans[op]
+		rr.run.Results = append(rr.run.Results, r)
+		rr.histograms = append(rr.histograms, taggedHistogram{
+			tag:       fmt.Sprintf("%s/%s/%s/w%d%s", backend, table, op, result.Workers, suffix),
+			start:     start,
+			duration:  result.Duration,
+			histogram: h,
+		})
+		rr.series[table] = append(rr.series[table], percentileSeries{backend: backend, op: op, histogram: h})
+	}
+
+	all := summarize(backend, table, "all", result, result.All(), int64(result.Errors), int64(result.Timeouts))
+	all.Server = result.Server
+	all.Pool = result.Pool
+	rr.run.Results = append(rr.run.Results, all)
+
+	failures := make([]failure, 0, len(result.messages))
+	for f := range result.messages {
+		failures = append(failures, f)
+	}
+	sort.Slice(failures, func(a, b int) bool {
+		if failures[a].op != failures[b].op {
+			return failures[a].op < failures[b].op
+		}
+		return failures[a].message < failures[b].message
+	})
+	for _, f := range failures {
+		rr.run.AddErrors(backend, table, f.op, f.message, int64(result.messages[f]))
+	}
+
+	if len(result.Samples) > 0 {
+		series := results.Series{
+			Backend:         backend,
+			Table:           table,
+			Workers:         result.Workers,
+			IntervalSeconds: rr.interval.Seconds(),
+			Samples:         result.Samples,
+		}
+		rr.run.Series = append(rr.run.Series, series)
+		rr.timeSeries[table] = append(rr.timeSeries[table], series)
+	}
+}
+
+func summarize(backend, table, op string, result Result, h *histogram.Histogram, errors, timeouts int64) results.Result {
+	// Like results.Iterations, throughput counts the successful operations only
+	operations := h.TotalCount() + errors + timeouts
+	throughput := 0.0
+	if result.Duration > 0 {
+		throughput = float64(h.TotalCount()) / result.Duration.Seconds()
+	}
+	r := results.Result{
+		Backend:         backend,
+		Table:           table,
+		Rows:            result.Rows,
+		Operation:       op,
+		Workers:         result.Workers,
+		PoolSize:        result.PoolSize,
+		Operations:      operations,
+		Errors:          errors,
+		Timeouts:        timeouts,
+		DurationSeconds: result.Duration.Seconds(),
+		Throughput:      throughput,
+		Latency:         results.Summarize(h),
+	}
+	r.Normalize()
+	return r
+}
+
+// save writes the histogram log and the percentile plots, once the results
+// are saved
+func (rr *runRecorder) save() error {
+	logPath := rr.run.Path(".hlog")
+	if err := rr.writeLog(logPath); err != nil {
+		return fmt.Errorf("writing the histogram log: %w", err)
+	}
+	fmt.Println("Latency histograms saved to", logPath)
+
+	// A sweep has too many runs per table for one plot each, compare them instead
+	if len(rr.run.Config.PoolSizes) > 0 {
+		for _, table := range rr.tables {
+			if err := plotPoolScaling(rr.run.Config.Workload, table, rr.run.Results); err != nil {
+				fmt.Printf("Error plotting pool scaling for %s: %v\n", table, err)
+			}
+		}
+		return nil
+	}
+	if len(rr.run.Config.Sweep) > 0 {
+		for _, table := range rr.tables {
+			if err := plotScalability(rr.run.Config.Workload, table, rr.run.Results); err != nil {
+				fmt.Printf("Error plotting scalability for %s: %v\n", table, err)
+			}
+		}
+		return nil
+	}
+
+	if len(rr.tables) > 1 {
+		if err := plotDatasetScaling(rr.run.Config.Workload, rr.run.Results); err != nil {
+			fmt.Println("Error plotting dataset scaling:", err)
+		}
+	}
+
+	for _, table := range rr.tables {
+		if err := plotPercentiles(rr.run.Config.Workload, table, rr.series[table]); err != nil {
+			fmt.Printf("Error plotting latency percentiles for %s: %v\n", table, err)
+		}
+		if series := rr.timeSeries[table]; len(series) > 0 {
+			if err := plotTimeSeries(rr.run.Config.Workload, table, series); err != nil {
+				fmt.Printf("Error plotting throughput and latency over time for %s: %v\n", table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeLog writes every histogram of the run to path
+func (rr *runRecorder) writeLog(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	log, err := histogram.NewLogWriter(file, rr.run.Started)
+	if err != nil {
+		return err
+	}
+	for _, th := range rr.histograms {
+		if err := log.Write(th.tag, th.start, th.duration, th.histogram); err != nil {
+			return err
+		}
+	}
+	return nil
+}
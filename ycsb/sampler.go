@@ -0,0 +1,76 @@
+package ycsb
+
+import (
+	"sync"
+	"time"
+
+	"benchmarkDB/histogram"
+	"benchmarkDB/results"
+)
+
+// DefaultSampleInterval is how often throughput and latency are sampled
+const DefaultSampleInterval = time.Second
+
+// sampler accumulates the operations completed by all workers in the
+// current interval and turns them into a sample when the interval ends
+type sampler struct {
+	mu       sync.Mutex
+	start    time.Time
+	last     time.Time
+	interval *histogram.Histogram
+	errors   int64
+	samples  []results.Sample
+}
+
+func newSampler(start time.Time) *sampler {
+	return &sampler{start: start, last: start, interval: histogram.NewLatency()}
+}
+
+// record adds a completed operation to the current interval
+func (s *sampler) record(elapsed time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.errors++
+		return
+	}
+	s.interval.RecordDuration(elapsed)
+}
+
+// sample closes the current interval
+func (s *sampler) sample(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	length := now.Sub(s.last)
+	if length <= 0 {
+		return
+	}
+	operations := s.interval.TotalCount() + s.errors
+	s.samples = append(s.samples, results.Sample{
+		ElapsedSeconds: now.Sub(s.start).Seconds(),
+		Operations:     operations,
+		Errors:         s.errors,
+		Throughput:     float64(s.interval.TotalCount()) / length.Seconds(),
+		P50:            float64(s.interval.ValueAtQuantile(0.50)),
+		P99:            float64(s.interval.ValueAtQuantile(0.99)),
+	})
+
+	s.interval.Reset()
+	s.errors = 0
+	s.last = now
+}
+
+// run samples every interval until done is closed
+func (s *sampler) run(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.sample(now)
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package ycsb
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"benchmarkDB/bench"
+	"benchmarkDB/dataset"
+	"benchmarkDB/fixture"
+)
+
+// DefaultSweep is the sequence of worker counts of a concurrency sweep
+var DefaultSweep = []int{1, 2, 4, 8, 16, 32}
+
+// ParseSweep parses a comma separated list of worker counts or pool sizes,
+// e.g. "1,2,4,8"
+func ParseSweep(s string) ([]int, error) {
+	var levels []int
+	for _, field := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid count %q", field)
+		}
+		levels = append(levels, n)
+	}
+	if len(levels) == 0 {
+		return nil, errors.New("no counts given")
+	}
+	return levels, nil
+}
+
+// RunSweep runs a core workload at each worker count against the given tables
+// on both MongoDB and MySQL, and plots throughput and latency against concurrency
+func RunSweep(name string, opts Options, tables []string, workers []int) {
+	workload, err := Lookup(name)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if len(workers) == 0 {
+		workers = DefaultSweep
+	}
+	opts.Workers = workers[0]
+	if err := opts.Validate(); err != nil {
+		fmt.Println("Invalid options:", err)
+		return
+	}
+
+	// Connected to both backends, starting from the seed data
+	ctx, b := bench.Start(tables)
+
+	seed := time.Now().UnixNano()
+	run := newSweepRecorder(workload, opts, workers)
+	run.run.Environment.Servers = b.Servers()
+
+	fmt.Printf("************Sweeping concurrency for YCSB workload %s (%s)***************\n", workload.Name, workload.Description)
+	for _, table := range tables {
+		if ctx.Err() != nil {
+			break
+		}
+		records, err := dataset.Load(table)
+		if err != nil {
+			fmt.Printf("Error loading keys for %s: %v\n", table, err)
+			run.run.AddError("", table, "load keys", err)
+			continue
+		}
+
+		for i, n := range workers {
+			if ctx.Err() != nil {
+				break
+			}
+			// Every worker count starts from the seed data rather than from what the
+			// previous one inserted and deleted
+			if i > 0 {
+				if err := fixture.Setup(ctx, b.Mongo, b.MySQL, []string{table}); err != nil {
+					if ctx.Err() == nil {
+						fmt.Printf("Error resetting %s: %v\n", table, err)
+						run.run.AddError("", table, "reset", err)
+					}
+					break
+				}
+			}
+			opts.Workers = n
+			fmt.Println(describeOptions(opts))
+
+			mongoResult, err := Run(ctx, b.Mongo, table, workload, records, opts, seed)
+			if err != nil {
+				fmt.Printf("Error running workload %s on MongoDB %s with %d workers: %v\n", workload.Name, table, n, err)
+				run.run.AddError("MongoDB", table, "all", err)
+			} else {
+				printResult("MongoDB", table, workload, mongoResult)
+				run.add("MongoDB", table, mongoResult)
+			}
+
+			mysqlResult, err := Run(ctx, b.MySQL, table, workload, records, opts, seed)
+			if err != nil {
+				fmt.Printf("Error running workload %s on MySQL %s with %d workers: %v\n", workload.Name, table, n, err)
+				run.run.AddError("MySQL", table, "all", err)
+			} else {
+				printResult("MySQL", table, workload, mysqlResult)
+				run.add("MySQL", table, mysqlResult)
+			}
+		}
+	}
+	fmt.Println("*************************************************************")
+
+	b.After(run.save)
+	b.Finish(run.run)
+}
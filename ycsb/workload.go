@@ -0,0 +1,158 @@
+package ycsb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Operation types issued by the core workloads
+const (
+	OpRead            = "read"
+	OpUpdate          = "update"
+	OpInsert          = "insert"
+	OpScan            = "scan"
+	OpReadModifyWrite = "read-modify-write"
+)
+
+// Request distributions used to pick the key of each operation
+const (
+	Zipfian = "zipfian"
+	Uniform = "uniform"
+	Latest  = "latest"
+)
+
+// Workload mirrors the properties of a YCSB core workload file
+type Workload struct {
+	Name                      string
+	Description               string
+	ReadProportion            float64
+	UpdateProportion          float64
+	InsertProportion          float64
+	ScanProportion            float64
+	ReadModifyWriteProportion float64
+	RequestDistribution       string
+	MaxScanLength             int
+}
+
+// Workloads holds the standard YCSB core workloads A through F
+var Workloads = map[string]Workload{
+	"a": {
+		Name:                "A",
+		Description:         "Update heavy",
+		ReadProportion:      0.5,
+		UpdateProportion:    0.5,
+		RequestDistribution: Zipfian,
+	},
+	"b": {
+		Name:                "B",
+		Description:         "Read mostly",
+		ReadProportion:      0.95,
+		UpdateProportion:    0.05,
+		RequestDistribution: Zipfian,
+	},
+	"c": {
+		Name:                "C",
+		Description:         "Read only",
+		ReadProportion:      1,
+		RequestDistribution: Zipfian,
+	},
+	"d": {
+		Name:                "D",
+		Description:         "Read latest",
+		ReadProportion:      0.95,
+		InsertProportion:    0.05,
+		RequestDistribution: Latest,
+	},
+	"e": {
+		Name:                "E",
+		Description:         "Short ranges",
+		ScanProportion:      0.95,
+		InsertProportion:    0.05,
+		RequestDistribution: Zipfian,
+		MaxScanLength:       100,
+	},
+	"f": {
+		Name:                      "F",
+		Description:               "Read-modify-write",
+		ReadProportion:            0.5,
+		ReadModifyWriteProportion: 0.5,
+		RequestDistribution:       Zipfian,
+	},
+}
+
+// Lookup returns the core workload with the given letter, e.g. "a" or "workloada"
+func Lookup(name string) (Workload, error) {
+	key := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "workload")
+	workload, ok := Workloads[key]
+	if !ok {
+		return Workload{}, fmt.Errorf("unknown workload %q (expected one of %s)", name, strings.Join(Names(), ", "))
+	}
+	return workload, nil
+}
+
+// Names lists the workload letters in order
+func Names() []string {
+	names := make([]string, 0, len(Workloads))
+	for name := range Workloads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// proportion is the share of an operation type in the workload mix
+func (w Workload) proportion(op string) float64 {
+	switch op {
+	case OpRead:
+		return w.ReadProportion
+	case OpUpdate:
+		return w.UpdateProportion
+	case OpInsert:
+		return w.InsertProportion
+	case OpScan:
+		return w.ScanProportion
+	case OpReadModifyWrite:
+		return w.ReadModifyWriteProportion
+	default:
+		return 0
+	}
+}
+
+// chooseOperation picks the next operation type according to the workload mix
+func (w Workload) chooseOperation(u float64) string {
+	mix := []struct {
+		op         string
+		proportion float64
+	}{
+		{OpRead, w.ReadProportion},
+		{OpUpdate, w.UpdateProportion},
+		{OpInsert, w.InsertProportion},
+		{OpScan, w.ScanProportion},
+		{OpReadModifyWrite, w.ReadModifyWriteProportion},
+	}
+
+	total := 0.0
+	for _, m := range mix {
+		total += m.proportion
+	}
+
+	u *= total
+	for _, m := range mix {
+		if m.proportion == 0 {
+			continue
+		}
+		if u < m.proportion {
+			return m.op
+		}
+		u -= m.proportion
+	}
+
+	// Rounding left us past the end, fall back to the last operation in the mix
+	for i := len(mix) - 1; i >= 0; i-- {
+		if mix[i].proportion > 0 {
+			return mix[i].op
+		}
+	}
+	return OpRead
+}
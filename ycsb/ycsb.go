@@ -0,0 +1,571 @@
+package ycsb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"benchmarkDB/bench"
+	"benchmarkDB/create"
+	"benchmarkDB/dataset"
+	"benchmarkDB/explain"
+	"benchmarkDB/histogram"
+	"benchmarkDB/pool"
+	"benchmarkDB/results"
+	"benchmarkDB/runctx"
+	"benchmarkDB/server"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultOperationCount matches the operationcount of the YCSB workload files
+const DefaultOperationCount = 1000
+
+// Options control how much load a workload offers and for how long
+type Options struct {
+	OperationCount int           // stop after this many operations, 0 for no limit
+	Duration       time.Duration // stop after this much time, 0 for no limit
+	TargetRate     float64       // operations per second across all workers, 0 to run as fast as possible
+	Workers        int           // concurrent clients issuing operations
+	SampleInterval time.Duration // how often throughput and latency are sampled, 0 to disable
+}
+
+// DefaultOptions runs DefaultOperationCount operations on a single worker
+func DefaultOptions() Options {
+	return Options{OperationCount: DefaultOperationCount, Workers: 1, SampleInterval: DefaultSampleInterval}
+}
+
+// Validate checks that the options describe a run that terminates
+func (o Options) Validate() error {
+	if o.OperationCount < 0 || o.Duration < 0 || o.TargetRate < 0 || o.SampleInterval < 0 {
+		return errors.New("operation count, duration, target rate and sample interval must not be negative")
+	}
+	if o.OperationCount == 0 && o.Duration == 0 {
+		return errors.New("either an operation count or a duration is required")
+	}
+	if o.Workers < 1 {
+		return errors.New("at least one worker is required")
+	}
+	return nil
+}
+
+// OpenLoop reports whether operations are scheduled at a fixed rate rather
+// than issued back to back
+func (o Options) OpenLoop() bool {
+	return o.TargetRate > 0
+}
+
+// Result of running a workload against one table on one backend.
+// In open-loop runs latencies are measured from the time an operation was
+// scheduled to start, so time spent waiting behind a slow operation counts.
+type Result struct {
+	Operations int
+	Errors     int
+	Timeouts   int
+	Workers    int
+	Rows       int // size of the table when the run started
+	TargetRate float64
+	Duration   time.Duration
+	Latencies  map[string]*histogram.Histogram // microseconds, keyed by operation type
+	Failures   map[string]int                  // failed operations, keyed by operation type
+	TimedOut   map[string]int                  // operations that ran out of time, keyed by operation type
+	Samples    []results.Sample                // throughput and latency over time
+	Server     results.Metrics                 // server status during the run, when collected
+	Plans      map[string]*results.Plan        // how the server runs each operation type, when explained
+	Pool       *results.Pool                   // connection checkouts during the run
+	PoolSize   int                             // connections the client was limited to, in a pool sweep
+	messages   map[failure]int                 // failed operations, keyed by operation type and error
+}
+
+// failure is an operation type together with the error it failed with
+type failure struct {
+	op      string
+	message string
+}
+
+func newResult() Result {
+	return Result{
+		Latencies: make(map[string]*histogram.Histogram),
+		Failures:  make(map[string]int),
+		TimedOut:  make(map[string]int),
+		messages:  make(map[failure]int),
+	}
+}
+
+// latency returns the histogram of an operation type, creating it on first use
+func (r *Result) latency(op string) *histogram.Histogram {
+	h, ok := r.Latencies[op]
+	if !ok {
+		h = histogram.NewLatency()
+		r.Latencies[op] = h
+	}
+	return h
+}
+
+// All merges the latencies of every operation type
+func (r Result) All() *histogram.Histogram {
+	all := histogram.NewLatency()
+	for _, h := range r.Latencies {
+		all.Merge(h)
+	}
+	return all
+}
+
+// merge adds the operations recorded by one worker
+func (r *Result) merge(other Result) {
+	r.Operations += other.Operations
+	r.Errors += other.Errors
+	r.Timeouts += other.Timeouts
+	for op, h := range other.Latencies {
+		r.latency(op).Merge(h)
+	}
+	for op, n := range other.Failures {
+		r.Failures[op] += n
+	}
+	for op, n := range other.TimedOut {
+		r.TimedOut[op] += n
+	}
+	for f, n := range other.messages {
+		r.messages[f] += n
+	}
+}
+
+// Throughput in successful operations per second, failed and timed out
+// operations are not counted
+func (r Result) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Operations-r.Errors-r.Timeouts) / r.Duration.Seconds()
+}
+
+// RunWorkload runs a core workload against every table on both MongoDB and MySQL
+func RunWorkload(name string, opts Options) {
+	workload, err := Lookup(name)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := opts.Validate(); err != nil {
+		fmt.Println("Invalid options:", err)
+		return
+	}
+
+	tables := []string{"table1", "table2", "table3", "table4"} // Representing MongoDB collections or MySQL tables
+
+	// Connected to both backends, starting from the seed data
+	ctx, b := bench.Start(tables)
+
+	// Both backends replay the same sequence of operations
+	seed := time.Now().UnixNano()
+	run := newRunRecorder(workload, opts)
+	run.run.Environment.Servers = b.Servers()
+
+	fmt.Printf("************Running YCSB workload %s (%s)***************\n", workload.Name, workload.Description)
+	fmt.Println(describeOptions(opts))
+	for _, table := range tables {
+		if ctx.Err() != nil {
+			break
+		}
+		records, err := dataset.Load(table)
+		if err != nil {
+			fmt.Printf("Error loading keys for %s: %v\n", table, err)
+			run.run.AddError("", table, "load keys", err)
+			continue
+		}
+
+		mongoResult, err := Run(ctx, b.Mongo, table, workload, records, opts, seed)
+		if err != nil {
+			fmt.Printf("Error running workload %s on MongoDB %s: %v\n", workload.Name, table, err)
+			run.run.AddError("MongoDB", table, "all", err)
+		} else {
+			printResult("MongoDB", table, workload, mongoResult)
+			run.add("MongoDB", table, mongoResult)
+		}
+
+		mysqlResult, err := Run(ctx, b.MySQL, table, workload, records, opts, seed)
+		if err != nil {
+			fmt.Printf("Error running workload %s on MySQL %s: %v\n", workload.Name, table, err)
+			run.run.AddError("MySQL", table, "all", err)
+		} else {
+			printResult("MySQL", table, workload, mysqlResult)
+			run.add("MySQL", table, mysqlResult)
+		}
+	}
+	fmt.Println("*************************************************************")
+
+	b.After(run.save)
+	b.Finish(run.run)
+}
+
+// Run issues operations of the workload against a single table until the
+// operation count or duration in opts is reached or ctx is done. records are
+// the rows the table was seeded with and form the initial keyspace.
+func Run(ctx context.Context, client interface{}, table string, workload Workload, records []create.Record, opts Options, seed int64) (Result, error) {
+	switch client.(type) {
+	case *mongo.Client, *sql.DB:
+	default:
+		return Result{}, errors.New("unsupported client type")
+	}
+	if err := opts.Validate(); err != nil {
+		return Result{}, err
+	}
+	if len(records) == 0 {
+		return Result{}, fmt.Errorf("no keys to run against in %s", table)
+	}
+
+	// As many keys as YCSB expects: the inserts of the run, twice over
+	expected := len(records)
+	if opts.OperationCount > 0 {
+		expected += int(2 * workload.proportion(OpInsert) * float64(opts.OperationCount))
+	}
+	chooser, err := newKeyChooser(workload.RequestDistribution, expected)
+	if err != nil {
+		return Result{}, err
+	}
+
+	keys := newKeyspace(records)
+	result := newResult()
+	result.Workers = opts.Workers
+	result.Rows = len(records)
+	result.TargetRate = opts.TargetRate
+
+	var issued int64
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	// Untimed, explaining the update does not change the row
+	if explain.Enabled {
+		result.Plans = explainOperations(ctx, client, table, workload, keys.get(0))
+	}
+
+	// Errors collecting the server metrics are recorded with the run's, not
+	// counted as failed operations
+	watch := server.Watch(ctx, client, func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.messages[failure{"server metrics", err.Error()}]++
+	})
+	connections := pool.Watch(client)
+	start := time.Now()
+	deadline := start.Add(opts.Duration)
+
+	samples := newSampler(start)
+	done := make(chan struct{})
+	var sampling sync.WaitGroup
+	if opts.SampleInterval > 0 {
+		sampling.Add(1)
+		go func() {
+			defer sampling.Done()
+			samples.run(opts.SampleInterval, done)
+		}()
+	}
+
+	for w := 0; w < opts.Workers; w++ {
+		wg.Add(1)
+		go func(r *rand.Rand) {
+			defer wg.Done()
+			local := newResult()
+
+			for ctx.Err() == nil {
+				n := atomic.AddInt64(&issued, 1) - 1
+				if opts.OperationCount > 0 && n >= int64(opts.OperationCount) {
+					break
+				}
+
+				// Closed loop: start now. Open loop: start at the scheduled time
+				// even when an earlier operation made us fall behind.
+				intended := time.Now()
+				if opts.OpenLoop() {
+					intended = start.Add(time.Duration(float64(n) / opts.TargetRate * float64(time.Second)))
+				}
+				if opts.Duration > 0 && !intended.Before(deadline) {
+					break
+				}
+				if wait := time.Until(intended); wait > 0 {
+					timer := time.NewTimer(wait)
+					select {
+					case <-timer.C:
+					case <-ctx.Done():
+						timer.Stop()
+					}
+				}
+
+				op := workload.chooseOperation(r.Float64())
+				err := runctx.Do(ctx, func(ctx context.Context) error {
+					return execute(ctx, client, table, workload, op, keys, chooser, r)
+				})
+				if runctx.Stopped(err) {
+					break
+				}
+				elapsed := time.Since(intended)
+				samples.record(elapsed, err)
+
+				local.Operations++
+				switch {
+				case errors.Is(err, runctx.ErrTimeout):
+					local.Timeouts++
+					local.TimedOut[op]++
+					local.messages[failure{op, err.Error()}]++
+					continue
+				case err != nil:
+					local.Errors++
+					local.Failures[op]++
+					local.messages[failure{op, err.Error()}]++
+					continue
+				}
+				local.latency(op).RecordDuration(elapsed)
+			}
+
+			mu.Lock()
+			result.merge(local)
+			mu.Unlock()
+		}(rand.New(rand.NewSource(seed + int64(w))))
+	}
+	wg.Wait()
+	close(done)
+	sampling.Wait()
+	result.Duration = time.Since(start)
+	result.Server = watch.Stop()
+	result.Pool = connections.Stop()
+
+	if opts.SampleInterval > 0 {
+		// Keep the last partial interval unless it is too short to be meaningful
+		if now := time.Now(); now.Sub(samples.last) >= opts.SampleInterval/10 {
+			samples.sample(now)
+		}
+		result.Samples = samples.samples
+	}
+
+	return result, nil
+}
+
+// describeOptions summarises the load mode for the console
+func describeOptions(opts Options) string {
+	var limits []string
+	if opts.OperationCount > 0 {
+		limits = append(limits, fmt.Sprintf("%d operations", opts.OperationCount))
+	}
+	if opts.Duration > 0 {
+		limits = append(limits, fmt.Sprint(opts.Duration))
+	}
+
+	mode := "closed loop, as fast as possible"
+	if opts.OpenLoop() {
+		mode = fmt.Sprintf("open loop at %.1f ops/sec", opts.TargetRate)
+	}
+	return fmt.Sprintf("Running %s with %d worker(s), %s", strings.Join(limits, " or "), opts.Workers, mode)
+}
+
+// execute performs a single operation of the workload
+func execute(ctx context.Context, client interface{}, table string, workload Workload, op string, keys *keyspace, chooser keyChooser, r *rand.Rand) error {
+	switch op {
+	case OpRead:
+		_, err := readRecord(ctx, client, table, keys.get(chooser.next(r, keys.size())))
+		return err
+	case OpUpdate:
+		return updateRecord(ctx, client, table, keys.get(chooser.next(r, keys.size())), randomEarnings(r))
+	case OpInsert:
+		key := keys.next()
+		record := create.Record{
+			Name:       key.Name,
+			School:     "YCSB",
+			Job:        "Benchmark User",
+			Department: "Benchmarking",
+			Earnings:   randomEarnings(r),
+			Year:       key.Year,
+		}
+		if err := insertRecord(ctx, client, table, record); err != nil {
+			return err
+		}
+		keys.add(key)
+		return nil
+	case OpScan:
+		length := 1 + r.Intn(workload.MaxScanLength)
+		return scanRecords(ctx, client, table, keys.get(chooser.next(r, keys.size())), length)
+	case OpReadModifyWrite:
+		key := keys.get(chooser.next(r, keys.size()))
+		record, err := readRecord(ctx, client, table, key)
+		if err != nil {
+			return err
+		}
+		return updateRecord(ctx, client, table, key, record.Earnings+float64(r.Intn(1000)))
+	default:
+		return fmt.Errorf("unknown operation %q", op)
+	}
+}
+
+func randomEarnings(r *rand.Rand) float64 {
+	return float64(20000 + r.Intn(100000))
+}
+
+func readRecord(ctx context.Context, client interface{}, table string, key Key) (create.Record, error) {
+	var record create.Record
+	switch c := client.(type) {
+	case *mongo.Client:
+		mongoClient := c
+		filter := bson.M{"Name": key.Name, "Year": key.Year}
+		err := mongoClient.Database("MONGODB_DATABASE").Collection(table).FindOne(ctx, filter).Decode(&record)
+		return record, err
+	case *sql.DB:
+		mysqlDB := c
+		err := mysqlDB.QueryRowContext(ctx, readQuery(table), key.Name, key.Year).Scan(&record.Name, &record.School, &record.Job, &record.Department, &record.Earnings, &record.Year)
+		return record, err
+	default:
+		return record, errors.New("unsupported client type")
+	}
+}
+
+// insertRecord stores a record with the same field names as the imported dataset
+func insertRecord(ctx context.Context, client interface{}, table string, record create.Record) error {
+	switch c := client.(type) {
+	case *mongo.Client:
+		mongoClient := c
+		document := bson.D{
+			{Key: "Name", Value: record.Name},
+			{Key: "School", Value: record.School},
+			{Key: "Job", Value: record.Job},
+			{Key: "Department", Value: record.Department},
+			{Key: "Earnings", Value: record.Earnings},
+			{Key: "Year", Value: record.Year},
+		}
+		_, err := mongoClient.Database("MONGODB_DATABASE").Collection(table).InsertOne(ctx, document)
+		return err
+	case *sql.DB:
+		mysqlDB := c
+		query := "INSERT INTO " + table + " (Name, School, Job, Department, Earnings, Year) VALUES (?, ?, ?, ?, ?, ?)"
+		_, err := mysqlDB.ExecContext(ctx, query, record.Name, record.School, record.Job, record.Department, record.Earnings, record.Year)
+		return err
+	default:
+		return errors.New("unsupported client type")
+	}
+}
+
+func updateRecord(ctx context.Context, client interface{}, table string, key Key, earnings float64) error {
+	switch c := client.(type) {
+	case *mongo.Client:
+		mongoClient := c
+		filter := bson.M{"Name": key.Name, "Year": key.Year}
+		update := bson.M{"$set": bson.M{"Earnings": earnings}}
+		_, err := mongoClient.Database("MONGODB_DATABASE").Collection(table).UpdateOne(ctx, filter, update)
+		return err
+	case *sql.DB:
+		mysqlDB := c
+		_, err := mysqlDB.ExecContext(ctx, updateQuery(table), earnings, key.Name, key.Year)
+		return err
+	default:
+		return errors.New("unsupported client type")
+	}
+}
+
+// scanRecords reads up to length records ordered by Name, starting at key
+func scanRecords(ctx context.Context, client interface{}, table string, key Key, length int) error {
+	switch c := client.(type) {
+	case *mongo.Client:
+		mongoClient := c
+		filter := bson.M{"Name": bson.M{"$gte": key.Name}}
+		opts := options.Find().SetSort(bson.D{{Key: "Name", Value: 1}}).SetLimit(int64(length))
+		cursor, err := mongoClient.Database("MONGODB_DATABASE").Collection(table).Find(ctx, filter, opts)
+		if err != nil {
+			return err
+		}
+		var records []create.Record
+		return cursor.All(ctx, &records)
+	case *sql.DB:
+		mysqlDB := c
+		rows, err := mysqlDB.QueryContext(ctx, scanQuery(table), key.Name, length)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var record create.Record
+			if err := rows.Scan(&record.Name, &record.School, &record.Job, &record.Department, &record.Earnings, &record.Year); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	default:
+		return errors.New("unsupported client type")
+	}
+}
+
+// MySQL statements of the operations, see explainOperations for how they
+// compare to the MongoDB ones
+func readQuery(table string) string {
+	return "SELECT Name, School, Job, Department, Earnings, Year FROM " + table + " WHERE Name = ? AND Year = ? LIMIT 1"
+}
+
+func updateQuery(table string) string {
+	return "UPDATE " + table + " SET Earnings = ? WHERE Name = ? AND Year = ?"
+}
+
+func scanQuery(table string) string {
+	return "SELECT Name, School, Job, Department, Earnings, Year FROM " + table + " WHERE Name >= ? ORDER BY Name LIMIT ?"
+}
+
+// explainOperations explains the queries of the operation types of a
+// workload for a key, the longest scan standing for the scans. Inserts have
+// no plan worth comparing and are left out.
+func explainOperations(ctx context.Context, client interface{}, table string, workload Workload, key Key) map[string]*results.Plan {
+	filter := bson.M{"Name": key.Name, "Year": key.Year}
+	read := explain.Query{
+		SQL:   readQuery(table),
+		Args:  []interface{}{key.Name, key.Year},
+		Mongo: explain.Find(table, filter, nil, 1),
+	}
+	queries := map[string]explain.Query{
+		OpRead:            read,
+		OpReadModifyWrite: read,
+		OpUpdate: {
+			SQL:   updateQuery(table),
+			Args:  []interface{}{0.0, key.Name, key.Year},
+			Mongo: explain.Update(table, filter, bson.M{"$set": bson.M{"Earnings": 0.0}}, false, false),
+		},
+		OpScan: {
+			SQL:   scanQuery(table),
+			Args:  []interface{}{key.Name, workload.MaxScanLength},
+			Mongo: explain.Find(table, bson.M{"Name": bson.M{"$gte": key.Name}}, bson.D{{Key: "Name", Value: 1}}, int64(workload.MaxScanLength)),
+		},
+	}
+	plans := make(map[string]*results.Plan)
+	for op, q := range queries {
+		if workload.proportion(op) > 0 {
+			plans[op] = explain.Run(ctx, client, q)
+		}
+	}
+	return plans
+}
+
+func printResult(backend, table string, workload Workload, result Result) {
+	fmt.Printf("Time taken for workload %s on %s in %s: %v (%.1f ops/sec, %d errors, %d timeouts)\n",
+		workload.Name, backend, dataset.Label(table), result.Duration, result.Throughput(), result.Errors, result.Timeouts)
+	if result.TargetRate > 0 && result.Throughput() < 0.95*result.TargetRate {
+		fmt.Printf("    Warning: %s could not keep up with the target of %.1f ops/sec\n", backend, result.TargetRate)
+	}
+
+	for _, op := range operationTypes(result) {
+		h := result.latency(op)
+		fmt.Printf("    %-17s %6d ops  p50 %v  p95 %v  p99 %v  max %v  (%d failed, %d timed out)\n", op, h.TotalCount(),
+			results.Micros(float64(h.ValueAtQuantile(0.50))), results.Micros(float64(h.ValueAtQuantile(0.95))), results.Micros(float64(h.ValueAtQuantile(0.99))), results.Micros(float64(h.Max())), result.Failures[op], result.TimedOut[op])
+	}
+}
+
+// operationTypes lists the operation types issued during a run in a fixed order
+func operationTypes(result Result) []string {
+	var ops []string
+	for _, op := range []string{OpRead, OpUpdate, OpInsert, OpScan, OpReadModifyWrite} {
+		if result.Latencies[op] != nil || result.Failures[op] > 0 || result.TimedOut[op] > 0 {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}